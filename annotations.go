@@ -0,0 +1,340 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// annotation is one change or risky finding, positioned in a source file, from
+// which the CI annotation formats (github, gitlab, sarif) are rendered.
+type annotation struct {
+	RuleID  string
+	Warning bool   // Risky finding rather than a plain change
+	Title   string // Object identity
+	Message string
+	Source  *sourcePosition
+}
+
+// annotationRule describes a rule for the SARIF rule table.
+type annotationRule struct {
+	ID          string
+	Description string
+}
+
+// annotationRules lists every rule an annotation can carry: one per kind of
+// change, followed by the risky findings detected by riskFinding.
+var annotationRules = []annotationRule{
+	{"object-added", "Object added"},
+	{"object-removed", "Object removed"},
+	{"field-added", "Field added"},
+	{"field-removed", "Field removed"},
+	{"field-modified", "Field modified"},
+	{"field-type-only", "Field changed representation only"},
+	{"container-removed", "Container removed"},
+	{"image-latest", "Container image uses the latest tag or no tag"},
+	{"privileged", "Container runs privileged"},
+	{"host-namespace", "Pod shares a host namespace"},
+	{"scaled-to-zero", "Workload scaled to zero replicas"},
+	{"limits-removed", "Resource limits removed"},
+}
+
+// buildAnnotations derives annotations from the change set: one per added or
+// removed object and one per field change. Changes matching a riskFinding rule
+// are reported as warnings under that rule; removed objects are always warnings.
+// Secret data and last-applied configurations are redacted.
+func buildAnnotations(objects1, objects2 []K8sObject) []annotation {
+	var annotations []annotation
+	for _, entry := range buildChangeSet(objects1, objects2).Objects {
+		switch entry.Status {
+		case "added":
+			annotations = append(annotations, annotation{RuleID: "object-added", Title: entry.Key, Message: entry.Key + " is added", Source: entry.Source})
+		case "removed":
+			annotations = append(annotations, annotation{RuleID: "object-removed", Warning: true, Title: entry.Key, Message: entry.Key + " is removed", Source: entry.Source})
+		case "modified":
+			for _, record := range entry.Changes {
+				a := annotation{RuleID: "field-" + string(record.Type), Title: entry.Key, Message: describeChangeRecord(entry, record), Source: record.Source}
+				if rule, reason := riskFinding(record); rule != "" {
+					a.RuleID, a.Warning = rule, true
+					a.Message = reason + ": " + a.Message
+				}
+				annotations = append(annotations, a)
+			}
+		}
+	}
+	return annotations
+}
+
+// describeChangeRecord renders a change as "path: old -> new".
+func describeChangeRecord(entry objectChangeSet, record changeRecord) string {
+	oldText, newText := flatValue(record.Old), flatValue(record.New)
+	keys := make([]string, len(record.Segments))
+	for i, seg := range record.Segments {
+		keys[i], _ = seg.(string)
+	}
+	if isSensitiveField(entry.Kind, keys) {
+		oldText, newText = redactedValue, redactedValue
+	}
+	switch record.Type {
+	case changeAdded:
+		return fmt.Sprintf("%s added: %s", record.Path, newText)
+	case changeRemoved:
+		return fmt.Sprintf("%s removed (was %s)", record.Path, oldText)
+	case changeTypeOnly:
+		return fmt.Sprintf("%s: %s -> %s (type-only change)", record.Path, oldText, newText)
+	}
+	return fmt.Sprintf("%s: %s -> %s", record.Path, oldText, newText)
+}
+
+// riskFinding checks a field change against the risky-change rules and returns
+// the rule ID and a short reason, or "" when the change is not risky. Added and
+// removed maps are searched as a whole, so adding a securityContext with
+// privileged: true or removing a resources block with limits is caught too.
+func riskFinding(record changeRecord) (string, string) {
+	if len(record.Segments) == 0 {
+		return "", ""
+	}
+	last := record.Segments[len(record.Segments)-1]
+	field, _ := last.(string)
+
+	if record.Type == changeRemoved {
+		if isNameSelector(last) {
+			return "container-removed", "container removed"
+		}
+		for _, leaf := range riskLeaves(record.Path, field, record.Old) {
+			if strings.Contains(leaf.Path, "resources.limits") {
+				return "limits-removed", "resource limits removed"
+			}
+		}
+		return "", ""
+	}
+
+	for _, leaf := range riskLeaves(record.Path, field, record.New) {
+		switch {
+		case leaf.Field == "image" && isUnpinnedImage(leaf.Value):
+			return "image-latest", "image uses the latest tag"
+		case leaf.Field == "privileged" && leaf.Value == true:
+			return "privileged", "container runs privileged"
+		case (leaf.Field == "hostNetwork" || leaf.Field == "hostPID" || leaf.Field == "hostIPC") && leaf.Value == true:
+			return "host-namespace", "pod shares a host namespace"
+		case leaf.Field == "replicas" && leaf.Value == 0:
+			return "scaled-to-zero", "workload scaled to zero"
+		}
+	}
+	return "", ""
+}
+
+// riskLeaf is a scalar found below a changed value, with its dotted path and
+// the name of the map key holding it.
+type riskLeaf struct {
+	Path  string
+	Field string
+	Value interface{}
+}
+
+// riskLeaves lists the scalars at and below val, which lives at path under
+// the map key field. List items inherit the key of their list.
+func riskLeaves(path, field string, val interface{}) []riskLeaf {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		var leaves []riskLeaf
+		for _, key := range sortedKeys(v) {
+			leaves = append(leaves, riskLeaves(path+"."+key, key, v[key])...)
+		}
+		return leaves
+	case []interface{}:
+		var leaves []riskLeaf
+		for i, item := range v {
+			leaves = append(leaves, riskLeaves(fmt.Sprintf("%s[%d]", path, i), field, item)...)
+		}
+		return leaves
+	}
+	return []riskLeaf{{Path: path, Field: field, Value: val}}
+}
+
+// isNameSelector reports whether a serialized path segment selects an item by name.
+func isNameSelector(seg interface{}) bool {
+	_, ok := seg.(nameSelector)
+	return ok
+}
+
+// isUnpinnedImage reports whether an image reference uses the "latest" tag or
+// no tag and no digest at all (which Kubernetes resolves to latest).
+func isUnpinnedImage(val interface{}) bool {
+	image, ok := val.(string)
+	if !ok || strings.Contains(image, "@") {
+		return false
+	}
+	name := image[strings.LastIndex(image, "/")+1:]
+	tag := ""
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		tag = name[i+1:]
+	}
+	return tag == "" || tag == "latest"
+}
+
+// writeGitHubAnnotations prints GitHub Actions workflow commands, which the
+// runner turns into inline pull request annotations:
+//
+//	::warning file=deploy.yaml,line=42,col=7,title=Deployment/prod/api::image uses the latest tag: ...
+//
+// Plain changes are notices and risky findings are warnings.
+func writeGitHubAnnotations(w io.Writer, objects1, objects2 []K8sObject) {
+	for _, a := range buildAnnotations(objects1, objects2) {
+		level := "notice"
+		if a.Warning {
+			level = "warning"
+		}
+		var properties []string
+		if a.Source != nil {
+			properties = append(properties,
+				"file="+escapeWorkflowProperty(a.Source.File),
+				fmt.Sprintf("line=%d", a.Source.Line),
+				fmt.Sprintf("col=%d", a.Source.Column))
+		}
+		properties = append(properties, "title="+escapeWorkflowProperty(a.Title))
+		fmt.Fprintf(w, "::%s %s::%s\n", level, strings.Join(properties, ","), escapeWorkflowData(a.Message))
+	}
+}
+
+// escapeWorkflowData escapes a workflow command message.
+func escapeWorkflowData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeWorkflowProperty escapes a workflow command property value.
+func escapeWorkflowProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// gitLabIssue is one entry of a GitLab Code Quality report.
+type gitLabIssue struct {
+	Description string         `json:"description"`
+	CheckName   string         `json:"check_name"`
+	Fingerprint string         `json:"fingerprint"`
+	Severity    string         `json:"severity"`
+	Location    gitLabLocation `json:"location"`
+}
+
+type gitLabLocation struct {
+	Path  string      `json:"path"`
+	Lines gitLabLines `json:"lines"`
+}
+
+type gitLabLines struct {
+	Begin int `json:"begin"`
+}
+
+// writeGitLabCodeQuality prints a GitLab Code Quality report (a JSON array of
+// issues) for use as an artifacts:reports:codequality file. Plain changes have
+// severity "info" and risky findings "major". Fingerprints are derived from
+// the rule, object and message so an unchanged finding keeps its identity
+// between pipelines.
+func writeGitLabCodeQuality(w io.Writer, objects1, objects2 []K8sObject) error {
+	issues := []gitLabIssue{}
+	for _, a := range buildAnnotations(objects1, objects2) {
+		issue := gitLabIssue{
+			Description: a.Title + ": " + a.Message,
+			CheckName:   a.RuleID,
+			Fingerprint: annotationFingerprint(a),
+			Severity:    "info",
+		}
+		if a.Warning {
+			issue.Severity = "major"
+		}
+		if a.Source != nil {
+			issue.Location = gitLabLocation{Path: a.Source.File, Lines: gitLabLines{Begin: a.Source.Line}}
+		}
+		issues = append(issues, issue)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(issues)
+}
+
+// annotationFingerprint returns a stable hex digest identifying an annotation.
+func annotationFingerprint(a annotation) string {
+	sum := sha256.Sum256([]byte(a.RuleID + "\x00" + a.Title + "\x00" + a.Message))
+	return hex.EncodeToString(sum[:16])
+}
+
+// writeSARIF prints a SARIF 2.1.0 log with a single run. Every rule is listed
+// in the tool driver; plain changes are "note" results and risky findings
+// "warning" results, each located at its source position.
+func writeSARIF(w io.Writer, objects1, objects2 []K8sObject) error {
+	type message struct {
+		Text string `json:"text"`
+	}
+	type rule struct {
+		ID               string  `json:"id"`
+		ShortDescription message `json:"shortDescription"`
+	}
+	type region struct {
+		StartLine   int `json:"startLine"`
+		StartColumn int `json:"startColumn,omitempty"`
+	}
+	type artifactLocation struct {
+		URI string `json:"uri"`
+	}
+	type physicalLocation struct {
+		ArtifactLocation artifactLocation `json:"artifactLocation"`
+		Region           region           `json:"region"`
+	}
+	type location struct {
+		PhysicalLocation physicalLocation `json:"physicalLocation"`
+	}
+	type result struct {
+		RuleID              string            `json:"ruleId"`
+		Level               string            `json:"level"`
+		Message             message           `json:"message"`
+		Locations           []location        `json:"locations,omitempty"`
+		PartialFingerprints map[string]string `json:"partialFingerprints"`
+	}
+
+	var rules []rule
+	for _, r := range annotationRules {
+		rules = append(rules, rule{ID: r.ID, ShortDescription: message{Text: r.Description}})
+	}
+
+	results := []result{}
+	for _, a := range buildAnnotations(objects1, objects2) {
+		res := result{
+			RuleID:              a.RuleID,
+			Level:               "note",
+			Message:             message{Text: a.Title + ": " + a.Message},
+			PartialFingerprints: map[string]string{"k8sDiff/v1": annotationFingerprint(a)},
+		}
+		if a.Warning {
+			res.Level = "warning"
+		}
+		if a.Source != nil {
+			res.Locations = []location{{PhysicalLocation: physicalLocation{
+				ArtifactLocation: artifactLocation{URI: a.Source.File},
+				Region:           region{StartLine: a.Source.Line, StartColumn: a.Source.Column},
+			}}}
+		}
+		results = append(results, res)
+	}
+
+	log := map[string]interface{}{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []interface{}{map[string]interface{}{
+			"tool": map[string]interface{}{
+				"driver": map[string]interface{}{
+					"name":  "k8s-diff",
+					"rules": rules,
+				},
+			},
+			"results": results,
+		}},
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(log)
+}
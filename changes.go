@@ -0,0 +1,334 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// changeType classifies a single field-level change.
+type changeType string
+
+const (
+	changeAdded    changeType = "added"     // Field exists only in the new object
+	changeRemoved  changeType = "removed"   // Field exists only in the old object
+	changeModified changeType = "modified"  // Field exists in both with different values
+	changeTypeOnly changeType = "type-only" // Same value in a different representation (--lenient)
+)
+
+// pathSegment is one step of a field path.
+//
+// Map fields are addressed by Key. Array items set Item and carry their
+// position in each version of the array (-1 where the item is absent).
+// Items of arrays matched by name, such as containers, also carry that Name.
+type pathSegment struct {
+	Key      string
+	Item     bool
+	Name     string
+	OldIndex int
+	NewIndex int
+}
+
+// fieldPath locates a value inside a Kubernetes object, starting at a
+// top-level section such as "metadata" or "spec".
+type fieldPath []pathSegment
+
+// String renders the path in the dotted form used throughout the reports:
+//
+//	spec.containers[name=nginx].image
+//	spec.ports[0].port
+//	data["app.properties"]
+//
+// Keys that contain dots, brackets or spaces are quoted to keep paths unambiguous.
+func (p fieldPath) String() string {
+	var b strings.Builder
+	for i, seg := range p {
+		switch {
+		case seg.Item && seg.Name != "":
+			fmt.Fprintf(&b, "[name=%s]", seg.Name)
+		case seg.Item:
+			fmt.Fprintf(&b, "[%d]", seg.index())
+		case strings.ContainsAny(seg.Key, ".[] \""):
+			fmt.Fprintf(&b, "[%q]", seg.Key)
+		default:
+			if i > 0 {
+				b.WriteString(".")
+			}
+			b.WriteString(seg.Key)
+		}
+	}
+	return b.String()
+}
+
+// index returns the item position to display, preferring the new version.
+func (s pathSegment) index() int {
+	if s.NewIndex >= 0 {
+		return s.NewIndex
+	}
+	return s.OldIndex
+}
+
+// child returns a copy of the path extended by one segment.
+// Copying keeps sibling paths from sharing a backing array.
+func (p fieldPath) child(seg pathSegment) fieldPath {
+	next := make(fieldPath, len(p), len(p)+1)
+	copy(next, p)
+	return append(next, seg)
+}
+
+// keySegment and itemSegment build path segments for map keys and array items.
+func keySegment(key string) pathSegment {
+	return pathSegment{Key: key, OldIndex: -1, NewIndex: -1}
+}
+
+func itemSegment(name string, oldIndex, newIndex int) pathSegment {
+	return pathSegment{Item: true, Name: name, OldIndex: oldIndex, NewIndex: newIndex}
+}
+
+// fieldChange is one leaf-level difference between two versions of an object.
+// Old is nil for additions and New is nil for removals.
+type fieldChange struct {
+	Path fieldPath
+	Type changeType
+	Old  interface{}
+	New  interface{}
+}
+
+// objectChanges lists every field change between two versions of an object,
+// in a stable order: top-level fields in objectFields order, map keys sorted,
+// containers in old-then-new order and other arrays by index.
+func objectChanges(obj1, obj2 K8sObject) []fieldChange {
+	var changes []fieldChange
+	for _, field := range unionFields(obj1, obj2) {
+		val1, ok1 := objectField(obj1, field)
+		val2, ok2 := objectField(obj2, field)
+		changes = append(changes, collectChanges(fieldPath{keySegment(field)}, val1, ok1, val2, ok2)...)
+	}
+	return changes
+}
+
+// collectChanges compares two values the same way diffAnyValue does and
+// returns the differences as fieldChange records instead of printing them:
+//
+//   - Maps are compared key by key
+//   - Container arrays are matched by container name
+//   - Other arrays of equal length are compared by index; arrays of different
+//     length are reported as a single replacement, like diffSlices
+//   - Scalars are compared directly, honoring --lenient
+func collectChanges(path fieldPath, val1 interface{}, ok1 bool, val2 interface{}, ok2 bool) []fieldChange {
+	switch {
+	case !ok1 && !ok2:
+		return nil
+	case !ok1:
+		return []fieldChange{{Path: path, Type: changeAdded, New: val2}}
+	case !ok2:
+		return []fieldChange{{Path: path, Type: changeRemoved, Old: val1}}
+	case reflect.DeepEqual(val1, val2):
+		return nil
+	}
+
+	switch v1 := val1.(type) {
+	case map[string]interface{}:
+		if v2, ok := val2.(map[string]interface{}); ok {
+			var changes []fieldChange
+			for _, key := range unionKeys(v1, v2) {
+				c1, has1 := v1[key]
+				c2, has2 := v2[key]
+				changes = append(changes, collectChanges(path.child(keySegment(key)), c1, has1, c2, has2)...)
+			}
+			return changes
+		}
+	case []interface{}:
+		if v2, ok := val2.([]interface{}); ok {
+			if isContainerArray(v1) && isContainerArray(v2) {
+				return collectContainerChanges(path, v1, v2)
+			}
+			if len(v1) == len(v2) {
+				var changes []fieldChange
+				for i := range v1 {
+					changes = append(changes, collectChanges(path.child(itemSegment("", i, i)), v1[i], true, v2[i], true)...)
+				}
+				return changes
+			}
+		}
+	default:
+		if opts.Lenient && !isComposite(val2) && scalarsEquivalent(val1, val2) {
+			return []fieldChange{{Path: path, Type: changeTypeOnly, Old: val1, New: val2}}
+		}
+	}
+
+	// Scalars, type mismatches and resized arrays are whole-value replacements
+	return []fieldChange{{Path: path, Type: changeModified, Old: val1, New: val2}}
+}
+
+// collectContainerChanges matches containers by name, like diffContainerArrays.
+func collectContainerChanges(path fieldPath, slice1, slice2 []interface{}) []fieldChange {
+	containers1, names1 := containersByName(slice1)
+	containers2, names2 := containersByName(slice2)
+
+	var changes []fieldChange
+	for _, name := range appendMissing(names1, names2) {
+		c1, has1 := containers1[name]
+		c2, has2 := containers2[name]
+		seg := itemSegment(name, indexOfContainer(slice1, name), indexOfContainer(slice2, name))
+		changes = append(changes, collectChanges(path.child(seg), c1, has1, c2, has2)...)
+	}
+	return changes
+}
+
+// indexOfContainer returns the array position of the named container, or -1.
+func indexOfContainer(slice []interface{}, name string) int {
+	for i, container := range slice {
+		if c, ok := container.(map[string]interface{}); ok && c["name"] == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// countChanges tallies field changes by type.
+func countChanges(changes []fieldChange) map[changeType]int {
+	counts := make(map[changeType]int)
+	for _, change := range changes {
+		counts[change.Type]++
+	}
+	return counts
+}
+
+// pairStatus describes what happened to an object between the two files:
+// "added", "removed", "modified" or "unchanged".
+func pairStatus(pair objectPair) string {
+	switch {
+	case pair.Old == nil:
+		return "added"
+	case pair.New == nil:
+		return "removed"
+	case sameObject(*pair.Old, *pair.New):
+		return "unchanged"
+	}
+	return "modified"
+}
+
+// renameNote describes a renamed pair as "renamed from <old key>, <n>% similar",
+// the way the text output labels it, or returns "" for any other pair.
+func renameNote(pair objectPair) string {
+	if pair.RenamedFrom == "" {
+		return ""
+	}
+	return fmt.Sprintf("renamed from %s, %d%% similar", pair.RenamedFrom, pair.Similarity)
+}
+
+// oldKey returns the identity of the old side of a pair: the key it had
+// before a rename, or the shared key.
+func oldKey(pair objectPair) string {
+	if pair.RenamedFrom != "" {
+		return pair.RenamedFrom
+	}
+	return pair.Key
+}
+
+// formatChange renders a field change as a single line, e.g.
+//
+//	~ spec.containers[name=nginx].image: nginx:1.21 -> nginx:1.22
+//
+// Values are written on one line with flatValue, so lists and maps appear in
+// full as compact JSON. When redact is set, values are replaced by
+// "<redacted>" so sensitive data (such as Secret contents) never ends up in a
+// report.
+func formatChange(change fieldChange, redact bool) string {
+	oldText, newText := flatValue(change.Old), flatValue(change.New)
+	if redact {
+		oldText, newText = redactedValue, redactedValue
+	}
+
+	switch change.Type {
+	case changeAdded:
+		return fmt.Sprintf("+ %s: %s", change.Path, newText)
+	case changeRemoved:
+		return fmt.Sprintf("- %s: %s", change.Path, oldText)
+	case changeTypeOnly:
+		return fmt.Sprintf("~= %s: %s -> %s (type-only change)", change.Path, oldText, newText)
+	}
+	return fmt.Sprintf("~ %s: %s -> %s", change.Path, oldText, newText)
+}
+
+// redactedValue replaces sensitive values in reports.
+const redactedValue = "<redacted>"
+
+// lastAppliedAnnotation is where "kubectl apply" records the applied object.
+// For Secrets it holds a full copy of their data.
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// isSensitivePath reports whether a field of the given object holds secret
+// material that must be redacted: the data and stringData of Secrets, and
+// their last-applied-configuration annotation. The metadata.annotations map as
+// a whole counts as sensitive too, since an added or removed map carries the
+// annotation with it.
+func isSensitivePath(obj K8sObject, path fieldPath) bool {
+	keys := make([]string, len(path))
+	for i, seg := range path {
+		keys[i] = seg.Key
+	}
+	return isSensitiveField(obj.Kind, keys)
+}
+
+// isSensitiveField is isSensitivePath for a path given as its map keys, with
+// "" standing for array items.
+func isSensitiveField(kind string, keys []string) bool {
+	if kind != "Secret" || len(keys) == 0 {
+		return false
+	}
+	switch keys[0] {
+	case "data", "stringData":
+		return true
+	case "metadata":
+		return len(keys) == 1 || keys[1] == "annotations" && (len(keys) == 2 || keys[2] == lastAppliedAnnotation)
+	}
+	return false
+}
+
+// redactObject returns a copy of obj that is safe to print in full:
+// for Secrets, every value under data and stringData, and the
+// last-applied-configuration annotation, is replaced by "<redacted>".
+// Other kinds are returned unchanged.
+func redactObject(obj K8sObject) K8sObject {
+	if obj.Kind != "Secret" {
+		return obj
+	}
+	if obj.Data != nil {
+		obj.Data = redactedMap(obj.Data)
+	}
+	if annotations, ok := obj.Metadata["annotations"].(map[string]interface{}); ok {
+		if _, ok := annotations[lastAppliedAnnotation]; ok {
+			metadata := make(map[string]interface{}, len(obj.Metadata))
+			for key, val := range obj.Metadata {
+				metadata[key] = val
+			}
+			redacted := make(map[string]interface{}, len(annotations))
+			for key, val := range annotations {
+				redacted[key] = val
+			}
+			redacted[lastAppliedAnnotation] = redactedValue
+			metadata["annotations"] = redacted
+			obj.Metadata = metadata
+		}
+	}
+	if stringData, ok := obj.Extra["stringData"].(map[string]interface{}); ok {
+		extra := make(map[string]interface{}, len(obj.Extra))
+		for field, val := range obj.Extra {
+			extra[field] = val
+		}
+		extra["stringData"] = redactedMap(stringData)
+		obj.Extra = extra
+	}
+	return obj
+}
+
+// redactedMap returns a map with the keys of m and every value redacted.
+func redactedMap(m map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(m))
+	for key := range m {
+		redacted[key] = redactedValue
+	}
+	return redacted
+}
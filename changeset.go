@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// changeSet is the exported, machine-readable form of a diff (--output json).
+// It records enough context - full objects for additions and removals, old and
+// new values for field changes - to be re-applied onto another manifest set
+// with the patch subcommand, which checks every recorded old value first.
+type changeSet struct {
+	Objects []objectChangeSet `json:"objects"`
+}
+
+// objectChangeSet records what happened to one object.
+// Status is "added", "removed" or "modified". Object holds the full added or
+// removed object; Changes holds the field changes of a modified object.
+// Source is where the object was read from (the old file for removals).
+type objectChangeSet struct {
+	Key        string                 `json:"key"`
+	Status     string                 `json:"status"`
+	APIVersion string                 `json:"apiVersion"`
+	Kind       string                 `json:"kind"`
+	Namespace  string                 `json:"namespace,omitempty"`
+	Name       string                 `json:"name"`
+	Object     map[string]interface{} `json:"object,omitempty"`
+	Changes    []changeRecord         `json:"changes,omitempty"`
+	Source     *sourcePosition        `json:"source,omitempty"`
+}
+
+// changeRecord is the serialized form of a fieldChange.
+//
+// Segments is the machine-readable path: strings are map keys, numbers are
+// array indices, and {"name": ..., "index": ...} objects select an item of a
+// name-matched array such as containers. Old and New are always present and
+// are null where the field is absent (New for removals, Old for additions).
+// Source is where the change lives (see changeSource).
+type changeRecord struct {
+	Path     string          `json:"path"`
+	Segments []interface{}   `json:"segments"`
+	Type     changeType      `json:"type"`
+	Old      interface{}     `json:"old"`
+	New      interface{}     `json:"new"`
+	Source   *sourcePosition `json:"source,omitempty"`
+}
+
+// nameSelector is the serialized path segment for a name-matched array item.
+// Index records the item's position in the new array (or the old one when it
+// was removed) and is used to place re-applied additions.
+type nameSelector struct {
+	Name  string `json:"name"`
+	Index int    `json:"index"`
+}
+
+// buildChangeSet converts the matched objects into an exportable change set.
+// Unchanged objects are omitted.
+func buildChangeSet(objects1, objects2 []K8sObject) changeSet {
+	set := changeSet{Objects: []objectChangeSet{}}
+	for _, pair := range matchObjects(objects1, objects2) {
+		status := pairStatus(pair)
+		if status == "unchanged" {
+			continue
+		}
+
+		obj := pair.New
+		if obj == nil {
+			obj = pair.Old
+		}
+		entry := objectChangeSet{
+			Key:        pair.Key,
+			Status:     status,
+			APIVersion: obj.APIVersion,
+			Kind:       obj.Kind,
+			Namespace:  getObjectNamespace(*obj),
+			Name:       getObjectName(*obj),
+		}
+		if pos, ok := pairSource(pair); ok {
+			entry.Source = &pos
+		}
+
+		switch status {
+		case "added", "removed":
+			entry.Object = objectToMap(*obj)
+		case "modified":
+			for _, change := range objectChanges(*pair.Old, *pair.New) {
+				record := changeRecord{
+					Path:     change.Path.String(),
+					Segments: change.Path.segments(),
+					Type:     change.Type,
+					Old:      change.Old,
+					New:      change.New,
+				}
+				if pos, ok := changeSource(pair, change); ok {
+					record.Source = &pos
+				}
+				entry.Changes = append(entry.Changes, record)
+			}
+		}
+
+		set.Objects = append(set.Objects, entry)
+	}
+	return set
+}
+
+// segments converts a path to its serialized segment list.
+func (p fieldPath) segments() []interface{} {
+	segments := make([]interface{}, 0, len(p))
+	for _, seg := range p {
+		switch {
+		case seg.Item && seg.Name != "":
+			segments = append(segments, nameSelector{Name: seg.Name, Index: seg.index()})
+		case seg.Item:
+			segments = append(segments, seg.index())
+		default:
+			segments = append(segments, seg.Key)
+		}
+	}
+	return segments
+}
+
+// writeChangeSet prints the change set as indented JSON.
+func writeChangeSet(w io.Writer, objects1, objects2 []K8sObject) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(buildChangeSet(objects1, objects2))
+}
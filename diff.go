@@ -25,10 +25,19 @@ License: MIT
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"math/big"
 	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -49,17 +58,164 @@ const helpText = `k8s-diff - A semantic Kubernetes manifest diff tool
 
 USAGE:
     k8s-diff [OPTIONS] <file1> <file2>
+    k8s-diff [OPTIONS] --live <manifests>
+    k8s-diff patch <base> <changes.json>
+    k8s-diff merge <base> <ours> <theirs>
+    k8s-diff [OPTIONS] git <rev1> [<rev2>] -- <path>...
+    k8s-diff [OPTIONS] helm <chart> [helm flags...] -- [<chart>] [helm flags...]
+    k8s-diff [OPTIONS] kustomize <side1> <side2>
+    k8s-diff [OPTIONS] env <side1> <side2>
+    k8s-diff [--live] snapshot <manifests> <output>
+    k8s-diff gitconfig
 
 ARGUMENTS:
-    <file1>    First Kubernetes manifest file
-    <file2>    Second Kubernetes manifest file
+    <file1>    First Kubernetes manifest file or directory
+    <file2>    Second Kubernetes manifest file or directory
+               (YAML or JSON, including concatenated JSON, JSON Lines
+               and List objects; directories are searched for .yaml,
+               .yml, .json and .jsonl files; .tar.gz and .tgz snapshot
+               archives are read too)
+
+COMMANDS:
+    patch         Apply a change set exported with --output json onto
+                  <base> and print the result; fails without output if
+                  any recorded old value no longer matches the base
+    merge         Three-way merge of <ours> and <theirs> against their
+                  common <base>; prints the merged manifests, or a
+                  per-field conflict report and exits with status 1
+    git           Diff the manifests under <path> between two git
+                  revisions, or a revision and the working tree, read
+                  from the repository without checking anything out
+    helm          Render a local chart on each side with helm template
+                  ($HELM_BIN or helm from the PATH) and diff the output;
+                  flags such as -f values.yaml are passed to helm, and
+                  objects are attributed to their # Source: template
+    kustomize     Diff two kustomizations (or a kustomization and plain
+                  manifests), built with kustomize build or kubectl
+                  kustomize; hash suffixes of generated ConfigMap and
+                  Secret names are stripped so they match across sides
+    env           Compare two environments (read like kustomize): objects
+                  of <side1> are mapped onto <side2> with the --map-*
+                  rules, and what still differs is reported per object
+                  and field as environment drift
+    snapshot      Write the objects of <manifests> (with --live: their
+                  state in the cluster) as a deterministic snapshot, one
+                  key-sorted file per object at Kind/namespace/name.yaml,
+                  into an empty directory or a .tar.gz/.tgz archive;
+                  snapshots can be diffed like any other input
+    gitconfig     Print the .gitattributes and git config snippet that
+                  makes git diff and git difftool use k8s-diff for YAML
+                  files (git's 7-argument external diff convention is
+                  detected automatically; /dev/null is an empty side)
 
 OPTIONS:
     -h, --help    Show this help message
+    --lenient     Treat equal scalars of different types as equal
+                  (e.g. "8080" vs 8080, "true" vs true, 30s vs 30000ms)
+                  and mark them as type-only changes
+    -o, --output <format>
+                  Output format (default: text)
+                    text     Color-coded semantic diff
+                    unified  Line-based unified diff of normalized YAML
+                    side-by-side
+                             Two-column view of old and new objects
+                    markdown Summary table and collapsible per-object
+                             field diffs for pull request comments
+                    html     Self-contained HTML report with navigation,
+                             search and before/after YAML
+                    jsonpatch
+                             RFC 6902 JSON Patch per modified object,
+                             keyed by Kind/Namespace/Name
+                    strategic
+                             Strategic merge patches, ready to use as a
+                             kustomize overlay
+                    mergepatch
+                             RFC 7386 JSON merge patches (e.g. for CRDs)
+                    json     Machine-readable change set for the patch
+                             command
+                    flat     One line per leaf change prefixed with the
+                             object and full field path, for grepping
+                    github   GitHub Actions workflow commands that annotate
+                             changed lines; risky changes are warnings
+                    gitlab   GitLab Code Quality report (JSON)
+                    sarif    SARIF 2.1.0 log for code scanning tools
+                    junit    JUnit XML report with one test case per
+                             object, failing when <file2> drifted from
+                             <file1>
+    -U, --context <n>
+                  Lines of context for unified output, and unchanged
+                  sibling fields shown around each change in text
+                  output (default: 3)
+    -M, --find-renames <n>
+                  Also pair removed and added objects of the same kind
+                  that are at least <n>% similar as renames (e.g. 50);
+                  by default only kustomize hash-suffixed names pair up
+    --no-renames  Disable rename detection
+    --full        Print the unchanged sections of changed objects in
+                  full instead of only the changed fields
+    --show-content
+                  Print the YAML body of added and removed objects
+    --content-lines <n>
+                  Lines shown per added or removed object body
+                  (default: 50, 0 = no limit)
+    --width <n>   Total width for side-by-side output
+                  (default: terminal width, $COLUMNS, or 120)
+    --wrap        Wrap long side-by-side values instead of truncating
+    --max-bytes <n>
+                  Size limit for markdown output; least important object
+                  sections are dropped first (default: 65000, 0 = no limit)
+    --live        Diff <manifests> against the live objects in the
+                  cluster (old side); live objects are reduced to the
+                  fields the manifests set, dropping server metadata,
+                  status and defaults
+    --kubeconfig <file>
+                  Kubeconfig for --live (default: first file in
+                  $KUBECONFIG, or ~/.kube/config)
+    --kube-context <name>
+                  Kubeconfig context for --live (default: current)
+    --live-fixtures <file>
+                  Take live objects from a recorded manifest file or
+                  directory (e.g. kubectl get -o yaml) instead of a
+                  cluster; implies --live
+    --map-namespace <from>=<to>
+                  env: map namespace <from> of <side1> to <to>
+    --map-name <from>=<to>
+                  env: replace <from> with <to> in object names
+                  (e.g. -staging=-prod)
+    --map-value <from>=<to>
+                  env: replace <from> with <to> in other string values,
+                  such as hostnames
+    --ignore-field <path>
+                  env: expect differences at <path> and below
+                  (e.g. spec.replicas)
+    --env-config <file>
+                  env: read more rules from a YAML file with namespaces,
+                  names and values maps and an ignore list
+    --stat        Show per-object field change counts, totals per kind
+                  and overall object totals instead of the full diff
+    --name-only   List only the identities of changed objects
+    --name-status List changed objects prefixed with A (added),
+                  D (removed) or M (modified)
 
 EXAMPLES:
     k8s-diff manifest1.yaml manifest2.yaml
     k8s-diff old-deployment.yaml new-deployment.yaml
+    k8s-diff --output unified -U 5 old.yaml new.yaml
+    k8s-diff -o side-by-side --wrap old.yaml new.yaml
+    k8s-diff --stat old.yaml new.yaml
+    k8s-diff --full old.yaml new.yaml
+    k8s-diff -o html old.yaml new.yaml > report.html
+    k8s-diff -o flat old.yaml new.yaml | grep image
+    k8s-diff -o junit expected/ live.yaml > drift.xml
+    k8s-diff -o json staging-old.yaml staging-new.yaml > changes.json
+    k8s-diff patch prod.yaml changes.json > prod-new.yaml
+    k8s-diff --live manifests/
+    k8s-diff --live snapshot manifests/ snapshots/2024-06-01.tgz
+    k8s-diff --stat git main HEAD -- deploy/
+    k8s-diff helm ./chart -f values-old.yaml -- -f values-new.yaml
+    k8s-diff kustomize overlays/staging overlays/prod
+    k8s-diff env --map-namespace staging=prod --ignore-field spec.replicas \
+        overlays/staging overlays/prod
 
 DESCRIPTION:
     k8s-diff compares Kubernetes manifest files semantically, understanding
@@ -69,12 +225,46 @@ DESCRIPTION:
     - Red: Removals and taint indicators (!)
     - Green: Additions
     - Yellow: Modifications (shown as ~~ old_value and ~> new_value)
-    - White: Unchanged elements
+    - White: Unchanged elements and type-only changes (shown as ~=)
 
     The taint indicator (!) appears with container additions/removals to
     highlight structural changes to container arrays.
 `
 
+// options holds the settings selected on the command line.
+// A single package-level instance is populated by main before diffing starts,
+// so the recursive diff functions can consult it without threading it through
+// every call.
+type options struct {
+	Lenient bool   // Compare scalars by meaning rather than by YAML type
+	Output  string // Output format, one of outputFormats (default "text")
+	Context int    // Lines of context around unified diff hunks, and unchanged fields around compact text changes
+	Width   int    // Terminal width for side-by-side output (0 = auto-detect)
+	Wrap    bool   // Wrap long side-by-side values instead of truncating them
+
+	MaxBytes int // Size limit for markdown reports (0 = unlimited)
+
+	Summary string // Overview mode replacing the detailed diff: "stat", "name-only" or "name-status"
+	Full    bool   // Print unchanged sections of changed objects in full (text output)
+
+	RenameThreshold int  // Minimum similarity percentage for pairing renames (0 = hash suffixes only)
+	NoRenames       bool // Disable rename detection altogether
+
+	ShowContent  bool // Print the body of added and removed objects (text output)
+	ContentLines int  // Line limit per added or removed object body (0 = unlimited)
+
+	Env       envRules // Mapping and ignore rules for the env command
+	EnvConfig string   // File with additional env rules
+
+	Live         bool   // Diff the manifests against the live cluster state
+	Kubeconfig   string // Kubeconfig for --live (default: $KUBECONFIG or ~/.kube/config)
+	KubeContext  string // Kubeconfig context for --live (default: current context)
+	LiveFixtures string // Recorded live objects used instead of a cluster
+}
+
+// opts is the active configuration for the current run.
+var opts options
+
 // K8sObject represents a Kubernetes resource with the most common fields.
 // This struct captures the essential structure of most Kubernetes objects
 // while using interface{} for flexible handling of varying content.
@@ -85,6 +275,9 @@ DESCRIPTION:
 //   - Metadata: Object metadata including name, namespace, labels, etc.
 //   - Data: Used primarily by ConfigMaps and Secrets
 //   - Spec: Resource specification used by most workload resources
+//   - Extra: Every other top-level field (e.g. Role rules, Secret type and
+//     stringData), kept so that objects round-trip as complete documents
+//   - Source: File, document, line and column of the object and its fields
 //
 // The omitempty tags ensure that nil fields don't appear in YAML output.
 type K8sObject struct {
@@ -93,6 +286,71 @@ type K8sObject struct {
 	Metadata   map[string]interface{} `yaml:"metadata"`
 	Data       map[string]interface{} `yaml:"data,omitempty"`
 	Spec       map[string]interface{} `yaml:"spec,omitempty"`
+	Extra      map[string]interface{} `yaml:",inline"`
+
+	Source *objectSource `yaml:"-"` // Where the object was read from; nil if it was not read from a file
+}
+
+// topLevelFields lists the K8sObject sections in the order they are rendered.
+var topLevelFields = []string{"apiVersion", "kind", "metadata", "data", "spec"}
+
+// objectFields returns the top-level fields of an object in rendering order:
+// the K8sObject sections followed by its extra fields sorted by name, the same
+// order normalizedYAML writes them in.
+func objectFields(obj K8sObject) []string {
+	fields := append([]string(nil), topLevelFields...)
+	extra := make([]string, 0, len(obj.Extra))
+	for field := range obj.Extra {
+		extra = append(extra, field)
+	}
+	sort.Strings(extra)
+	return append(fields, extra...)
+}
+
+// unionFields returns the top-level fields of two objects in rendering order,
+// so that a field present on only one side is still compared.
+func unionFields(obj1, obj2 K8sObject) []string {
+	merged := obj1
+	merged.Extra = make(map[string]interface{}, len(obj1.Extra)+len(obj2.Extra))
+	for field, val := range obj1.Extra {
+		merged.Extra[field] = val
+	}
+	for field, val := range obj2.Extra {
+		merged.Extra[field] = val
+	}
+	return objectFields(merged)
+}
+
+// objectField returns a top-level section or extra field of an object by its
+// YAML name. The boolean is false when the section is absent (empty string or nil map),
+// mirroring how the omitempty tags drop it from YAML output.
+func objectField(obj K8sObject, field string) (interface{}, bool) {
+	switch field {
+	case "apiVersion":
+		return obj.APIVersion, obj.APIVersion != ""
+	case "kind":
+		return obj.Kind, obj.Kind != ""
+	case "metadata":
+		return obj.Metadata, obj.Metadata != nil
+	case "data":
+		return obj.Data, obj.Data != nil
+	case "spec":
+		return obj.Spec, obj.Spec != nil
+	}
+	val, ok := obj.Extra[field]
+	return val, ok
+}
+
+// objectToMap returns the present top-level sections of an object as a map,
+// the generic form used when building patches.
+func objectToMap(obj K8sObject) map[string]interface{} {
+	result := make(map[string]interface{})
+	for _, field := range objectFields(obj) {
+		if val, ok := objectField(obj, field); ok {
+			result[field] = val
+		}
+	}
+	return result
 }
 
 // main orchestrates the entire diff process:
@@ -114,15 +372,71 @@ func main() {
 		os.Exit(0) // Success exit for explicit help request
 	}
 
+	// Separate option flags from positional file arguments
+	parsed, files, err := parseArgs(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		fmt.Print(helpText)
+		os.Exit(1)
+	}
+	opts = parsed
+
+	// Patches address their target objects by name, so a renamed object must
+	// stay a removal plus an addition in the patch formats. The same goes for
+	// JUnit reports, whose test cases are object identities: a missing and an
+	// unexpected object are two failures, not one drift.
+	if contains(patchFormats, opts.Output) || opts.Output == "junit" {
+		opts.NoRenames = true
+	}
+
+	// Subcommands take over argument handling from here
+	if len(files) > 0 {
+		switch files[0] {
+		case "patch":
+			runPatchCommand(files[1:])
+			return
+		case "merge":
+			runMergeCommand(files[1:])
+			return
+		case "git":
+			runGitCommand(files[1:])
+			return
+		case "helm":
+			runHelmCommand(files[1:])
+			return
+		case "kustomize":
+			runKustomizeCommand(files[1:])
+			return
+		case "env":
+			runEnvCommand(files[1:])
+			return
+		case "snapshot":
+			runSnapshotCommand(files[1:])
+			return
+		case "gitconfig":
+			runGitConfigCommand(files[1:])
+			return
+		}
+	}
+	if opts.Live {
+		runLiveDiff(files)
+		return
+	}
+	if isExternalDiffArgs(files) {
+		runExternalDiff(files)
+		return
+	}
+	files = withoutSeparator(files)
+
 	// Validate argument count - exactly 2 file paths required
-	if len(args) != 2 {
-		fmt.Fprintf(os.Stderr, "Error: Expected exactly 2 file arguments, got %d\n\n", len(args))
+	if len(files) != 2 {
+		fmt.Fprintf(os.Stderr, "Error: Expected exactly 2 file arguments, got %d\n\n", len(files))
 		fmt.Print(helpText)
 		os.Exit(1)
 	}
 
-	file1 := args[0]
-	file2 := args[1]
+	file1 := files[0]
+	file2 := files[1]
 
 	// Verify both files exist before attempting to parse them
 	if err := checkFileExists(file1); err != nil {
@@ -147,8 +461,74 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Perform semantic diff and output results
-	diffK8sObjects(objects1, objects2)
+	writeDiff(file1, file2, objects1, objects2)
+}
+
+// writeDiff prints the diff of two parsed manifest sets in the format selected
+// by the options. file1 and file2 label the inputs in reports that name them.
+func writeDiff(file1, file2 string, objects1, objects2 []K8sObject) {
+	// Overview modes replace the detailed diff
+	switch opts.Summary {
+	case "stat":
+		writeStat(os.Stdout, objects1, objects2)
+		return
+	case "name-only", "name-status":
+		writeNames(os.Stdout, objects1, objects2, opts.Summary == "name-status")
+		return
+	}
+
+	// Perform semantic diff and output results in the requested format
+	switch opts.Output {
+	case "unified":
+		writeUnifiedDiff(os.Stdout, objects1, objects2)
+	case "side-by-side":
+		writeSideBySide(os.Stdout, objects1, objects2)
+	case "markdown":
+		writeMarkdownReport(os.Stdout, objects1, objects2)
+	case "html":
+		writeHTMLReport(os.Stdout, file1, file2, objects1, objects2)
+	case "jsonpatch":
+		if err := writeJSONPatches(os.Stdout, objects1, objects2); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to encode JSON patches: %v\n", err)
+			os.Exit(1)
+		}
+	case "strategic":
+		if err := writeStrategicMergePatches(os.Stdout, objects1, objects2); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to encode strategic merge patches: %v\n", err)
+			os.Exit(1)
+		}
+	case "mergepatch":
+		if err := writeJSONMergePatches(os.Stdout, objects1, objects2); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to encode merge patches: %v\n", err)
+			os.Exit(1)
+		}
+	case "json":
+		if err := writeChangeSet(os.Stdout, objects1, objects2); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to encode change set: %v\n", err)
+			os.Exit(1)
+		}
+	case "flat":
+		writeFlat(os.Stdout, objects1, objects2)
+	case "github":
+		writeGitHubAnnotations(os.Stdout, objects1, objects2)
+	case "gitlab":
+		if err := writeGitLabCodeQuality(os.Stdout, objects1, objects2); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to encode code quality report: %v\n", err)
+			os.Exit(1)
+		}
+	case "junit":
+		if err := writeJUnitReport(os.Stdout, objects1, objects2); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to encode JUnit report: %v\n", err)
+			os.Exit(1)
+		}
+	case "sarif":
+		if err := writeSARIF(os.Stdout, objects1, objects2); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to encode SARIF log: %v\n", err)
+			os.Exit(1)
+		}
+	default:
+		diffK8sObjects(objects1, objects2)
+	}
 }
 
 // contains checks if a string slice contains a specific string.
@@ -162,6 +542,200 @@ func contains(slice []string, item string) bool {
 	return false
 }
 
+// parseArgs splits the command line into options and positional arguments.
+// Flags may appear before, between, or after the file arguments.
+// Returns an error for any flag that is not recognized.
+func parseArgs(args []string) (options, []string, error) {
+	parsed := options{Output: "text", Context: 3, MaxBytes: 65000, ContentLines: 50}
+	var positional []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		// Support both "--flag value" and "--flag=value" spellings
+		name, value, hasValue := strings.Cut(arg, "=")
+		takeValue := func() (string, error) {
+			if hasValue {
+				return value, nil
+			}
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("option '%s' requires a value", name)
+			}
+			i++
+			return args[i], nil
+		}
+
+		switch {
+		case arg == "--lenient":
+			parsed.Lenient = true
+		case name == "-o" || name == "--output":
+			format, err := takeValue()
+			if err != nil {
+				return parsed, nil, err
+			}
+			if !contains(outputFormats, format) {
+				return parsed, nil, fmt.Errorf("unknown output format '%s' (expected one of: %s)", format, strings.Join(outputFormats, ", "))
+			}
+			parsed.Output = format
+		case name == "-U" || name == "--context":
+			raw, err := takeValue()
+			if err != nil {
+				return parsed, nil, err
+			}
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 0 {
+				return parsed, nil, fmt.Errorf("option '%s' expects a non-negative integer, got '%s'", name, raw)
+			}
+			parsed.Context = n
+		case name == "--width":
+			raw, err := takeValue()
+			if err != nil {
+				return parsed, nil, err
+			}
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 20 {
+				return parsed, nil, fmt.Errorf("option '%s' expects an integer of at least 20, got '%s'", name, raw)
+			}
+			parsed.Width = n
+		case arg == "--wrap":
+			parsed.Wrap = true
+		case name == "--max-bytes":
+			raw, err := takeValue()
+			if err != nil {
+				return parsed, nil, err
+			}
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 0 {
+				return parsed, nil, fmt.Errorf("option '%s' expects a non-negative integer, got '%s'", name, raw)
+			}
+			parsed.MaxBytes = n
+		case name == "-M" || name == "--find-renames":
+			raw, err := takeValue()
+			if err != nil {
+				return parsed, nil, err
+			}
+			n, err := strconv.Atoi(strings.TrimSuffix(raw, "%"))
+			if err != nil || n < 1 || n > 100 {
+				return parsed, nil, fmt.Errorf("option '%s' expects a percentage between 1 and 100, got '%s'", name, raw)
+			}
+			parsed.RenameThreshold = n
+			parsed.NoRenames = false
+		case arg == "--no-renames":
+			parsed.NoRenames = true
+		case arg == "--show-content":
+			parsed.ShowContent = true
+		case name == "--content-lines":
+			raw, err := takeValue()
+			if err != nil {
+				return parsed, nil, err
+			}
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 0 {
+				return parsed, nil, fmt.Errorf("option '%s' expects a non-negative integer, got '%s'", name, raw)
+			}
+			parsed.ContentLines = n
+		case name == "--map-namespace" || name == "--map-name" || name == "--map-value":
+			raw, err := takeValue()
+			if err != nil {
+				return parsed, nil, err
+			}
+			from, to, ok := strings.Cut(raw, "=")
+			if !ok || from == "" {
+				return parsed, nil, fmt.Errorf("option '%s' expects <from>=<to>, got '%s'", name, raw)
+			}
+			rule := mappingRule{From: from, To: to}
+			switch name {
+			case "--map-namespace":
+				parsed.Env.Namespaces = append(parsed.Env.Namespaces, rule)
+			case "--map-name":
+				parsed.Env.Names = append(parsed.Env.Names, rule)
+			default:
+				parsed.Env.Values = append(parsed.Env.Values, rule)
+			}
+		case name == "--ignore-field":
+			path, err := takeValue()
+			if err != nil {
+				return parsed, nil, err
+			}
+			parsed.Env.Ignore = append(parsed.Env.Ignore, path)
+		case name == "--env-config":
+			file, err := takeValue()
+			if err != nil {
+				return parsed, nil, err
+			}
+			parsed.EnvConfig = file
+		case arg == "--live":
+			parsed.Live = true
+		case name == "--kubeconfig" || name == "--kube-context" || name == "--live-fixtures":
+			raw, err := takeValue()
+			if err != nil {
+				return parsed, nil, err
+			}
+			switch name {
+			case "--kubeconfig":
+				parsed.Kubeconfig = raw
+			case "--kube-context":
+				parsed.KubeContext = raw
+			default:
+				parsed.LiveFixtures = raw
+				parsed.Live = true
+			}
+		case arg == "--full":
+			parsed.Full = true
+		case arg == "--stat" || arg == "--name-only" || arg == "--name-status":
+			if parsed.Summary != "" && parsed.Summary != arg[2:] {
+				return parsed, nil, fmt.Errorf("option '%s' cannot be combined with '--%s'", arg, parsed.Summary)
+			}
+			parsed.Summary = arg[2:]
+		case arg == "--":
+			// Everything after "--" is positional; the separator itself is kept
+			// for subcommands that use it to delimit paths
+			positional = append(positional, args[i:]...)
+			i = len(args)
+		case len(positional) == 0 && contains(passthroughCommands, arg):
+			// The rest of the line belongs to the command, which forwards its
+			// flags to another tool
+			positional = append(positional, args[i:]...)
+			i = len(args)
+		case strings.HasPrefix(arg, "-"):
+			return parsed, nil, fmt.Errorf("unknown option '%s'", arg)
+		default:
+			positional = append(positional, arg)
+		}
+	}
+
+	if (parsed.Env.active() || parsed.EnvConfig != "") && (len(positional) == 0 || positional[0] != "env") {
+		return parsed, nil, fmt.Errorf("options --map-*, --ignore-field and --env-config only apply to the env command")
+	}
+
+	if parsed.Summary != "" && parsed.Output != "text" {
+		return parsed, nil, fmt.Errorf("option '--%s' cannot be combined with --output %s", parsed.Summary, parsed.Output)
+	}
+
+	return parsed, positional, nil
+}
+
+// passthroughCommands lists the subcommands whose arguments are not parsed as
+// k8s-diff options; options for these must come before the command name.
+var passthroughCommands = []string{"helm"}
+
+// patchFormats lists the --output formats that produce machine-applicable patches.
+var patchFormats = []string{"jsonpatch", "strategic", "mergepatch", "json"}
+
+// outputFormats lists the values accepted by --output.
+var outputFormats = []string{"text", "unified", "side-by-side", "markdown", "html", "jsonpatch", "strategic", "mergepatch", "json", "flat", "github", "gitlab", "sarif", "junit"}
+
+// withoutSeparator drops the "--" end-of-options marker from positional arguments.
+func withoutSeparator(args []string) []string {
+	var result []string
+	for _, arg := range args {
+		if arg != "--" {
+			result = append(result, arg)
+		}
+	}
+	return result
+}
+
 // checkFileExists verifies that a file exists and is accessible.
 // Returns a descriptive error if the file doesn't exist or can't be accessed.
 func checkFileExists(filename string) error {
@@ -172,50 +746,195 @@ func checkFileExists(filename string) error {
 	return err
 }
 
-// parseK8sObjects reads a YAML file and parses it into a slice of K8sObject structs.
-// Handles multi-document YAML files by splitting on "---" separators.
+// parseK8sObjects reads the objects of a manifest file, directory or snapshot
+// archive (see parseSnapshotArchive). A directory is searched recursively for
+// manifest files (see isManifestFile), which are read in lexical path order so
+// the result is deterministic.
+func parseK8sObjects(path string) ([]K8sObject, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		if isSnapshotArchive(path) {
+			return parseSnapshotArchive(path)
+		}
+		return parseManifestFile(path)
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && isManifestFile(file) {
+			files = append(files, file)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []K8sObject
+	for _, file := range files {
+		fileObjects, err := parseManifestFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		objects = append(objects, fileObjects...)
+	}
+	return objects, nil
+}
+
+// isManifestFile reports whether a file found in a directory holds manifests:
+// YAML (.yaml, .yml) or JSON (.json, .jsonl).
+func isManifestFile(file string) bool {
+	switch filepath.Ext(file) {
+	case ".yaml", ".yml", ".json", ".jsonl":
+		return true
+	}
+	return false
+}
+
+// parseManifestFile reads a YAML or JSON file and parses it into a slice of K8sObject structs.
+// Handles multi-document YAML files separated by "---", concatenated JSON and
+// JSON Lines, and expands List objects.
 // Validates that each object has the required Kubernetes fields.
 //
 // The function:
 // 1. Reads the entire file content
-// 2. Splits by "---" to handle multiple Kubernetes objects
-// 3. Parses each document as a separate K8sObject
+// 2. Decodes the YAML stream (or the JSON values) one document at a time
+// 3. Parses each document as a separate K8sObject, or one per item of a List
 // 4. Validates each object for required Kubernetes fields
-// 5. Skips empty documents
+// 5. Records the source position of the object and its fields (see objectSource)
+// 6. Skips empty documents
 //
 // Returns: slice of parsed and validated objects and any parsing/validation error
-func parseK8sObjects(filename string) ([]K8sObject, error) {
+func parseManifestFile(filename string) ([]K8sObject, error) {
 	content, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
+	return parseManifestData(filename, content)
+}
+
+// parseManifestData parses manifest content that was read from somewhere
+// other than a plain file. name is recorded as the source file of every object.
+func parseManifestData(name string, content []byte) ([]K8sObject, error) {
+	// JSON documents, concatenated JSON and JSON Lines are not valid YAML
+	// streams, so they are split with the JSON decoder instead
+	if looksLikeJSON(content) {
+		objects, err := parseJSONManifests(name, content)
+		if err == nil || !isYAMLStream(content) {
+			return objects, err // Neither JSON nor YAML; the JSON error is the relevant one
+		}
+	}
+	return parseYAMLManifests(name, content)
+}
 
-	// Split by --- to handle multiple objects in a single YAML file
-	docs := strings.Split(string(content), "---")
+// isYAMLStream reports whether content is syntactically valid YAML, such as a
+// flow mapping ("{a: b}") that is not JSON.
+func isYAMLStream(content []byte) bool {
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); err == io.EOF {
+			return true
+		} else if err != nil {
+			return false
+		}
+	}
+}
+
+// parseYAMLManifests decodes a YAML stream document by document to handle
+// multiple objects in a single file, keeping each document's node tree for
+// source positions.
+func parseYAMLManifests(name string, content []byte) ([]K8sObject, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
 	var objects []K8sObject
 
-	for i, doc := range docs {
-		doc = strings.TrimSpace(doc)
-		if doc == "" {
+	for i := 1; ; i++ {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to parse object %d: %v", i, err)
+		}
+		if len(doc.Content) == 0 || doc.Content[0].Tag == "!!null" {
 			continue // Skip empty documents
 		}
 
+		docObjects, err := nodeObjects(name, i, doc.Content[0])
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, docObjects...)
+	}
+
+	return objects, nil
+}
+
+// nodeObjects decodes and validates the objects of one document. A List
+// (kind "List" or "<Kind>List" with an items array, as written by kubectl get)
+// and a top-level array are expanded into their items.
+func nodeObjects(name string, doc int, root *yaml.Node) ([]K8sObject, error) {
+	items := listItems(root)
+	if items == nil {
+		items = []*yaml.Node{root}
+	}
+
+	var objects []K8sObject
+	for _, item := range items {
 		var obj K8sObject
-		if err := yaml.Unmarshal([]byte(doc), &obj); err != nil {
-			return nil, fmt.Errorf("failed to parse object %d: %v", i+1, err)
+		if err := item.Decode(&obj); err != nil {
+			return nil, fmt.Errorf("failed to parse object %d: %v", doc, err)
 		}
 
 		// Validate the parsed object
-		if err := validateK8sObject(obj, i+1); err != nil {
+		if err := validateK8sObject(obj, doc); err != nil {
 			return nil, err
 		}
 
+		obj.Source = newObjectSource(name, doc, item)
 		objects = append(objects, obj)
 	}
-
 	return objects, nil
 }
 
+// listItems returns the item nodes of a List or top-level array, or nil when
+// the node is a single object.
+func listItems(root *yaml.Node) []*yaml.Node {
+	if root.Kind == yaml.SequenceNode {
+		return append([]*yaml.Node{}, root.Content...)
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var kind string
+	var items *yaml.Node
+	hasName := false
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, val := root.Content[i].Value, root.Content[i+1]
+		switch key {
+		case "kind":
+			kind = val.Value
+		case "items":
+			items = val
+		case "metadata":
+			for j := 0; j+1 < len(val.Content); j += 2 {
+				hasName = hasName || val.Content[j].Value == "name"
+			}
+		}
+	}
+	// Named objects are never lists, even if their kind ends in "List"
+	if !strings.HasSuffix(kind, "List") || items == nil || items.Kind != yaml.SequenceNode || hasName {
+		return nil
+	}
+	return append([]*yaml.Node{}, items.Content...)
+}
+
 // validateK8sObject checks that a parsed object has the required Kubernetes fields.
 // All Kubernetes objects must have: apiVersion, kind, and metadata.name.
 // The metadata.namespace field is optional (defaults to "default" when not specified).
@@ -266,9 +985,9 @@ func validateK8sObject(obj K8sObject, objNum int) error {
 // diffK8sObjects performs the high-level comparison between two sets of Kubernetes objects.
 //
 // Algorithm:
-// 1. Create lookup maps keyed by "Kind/Name" for O(1) object identification
-// 2. Find objects that exist only in file1 (removals)
-// 3. Find objects that exist only in file2 (additions)
+// 1. Match objects across files by "Kind/Name" (see matchObjects)
+// 2. Report objects that exist only in file1 (removals)
+// 3. Report objects that exist only in file2 (additions)
 // 4. Compare objects that exist in both files (modifications)
 //
 // This approach handles:
@@ -276,42 +995,111 @@ func validateK8sObject(obj K8sObject, objNum int) error {
 // - Objects that exist in both but have different content
 // - Maintains object identity across comparisons
 func diffK8sObjects(objects1, objects2 []K8sObject) {
+	pairs := matchObjects(objects1, objects2)
+
+	// Identify objects removed (exist in file1 but not file2)
+	for _, pair := range pairs {
+		if pair.New == nil {
+			fmt.Printf("%s- %s %s (removed)%s%s\n", ColorRed, pair.Old.Kind, getObjectName(*pair.Old), ColorReset, objectSourceSuffix(*pair.Old))
+			if opts.ShowContent {
+				printObjectContent(*pair.Old, ColorRed)
+			}
+		}
+	}
+
+	// Identify objects added (exist in file2 but not file1)
+	for _, pair := range pairs {
+		if pair.Old == nil {
+			fmt.Printf("%s+ %s %s (added)%s%s\n", ColorGreen, pair.New.Kind, getObjectName(*pair.New), ColorReset, objectSourceSuffix(*pair.New))
+			if opts.ShowContent {
+				printObjectContent(*pair.New, ColorGreen)
+			}
+		}
+	}
+
+	// Identify objects renamed or moved to another namespace
+	for _, pair := range pairs {
+		if pair.RenamedFrom != "" {
+			fmt.Printf("%s~ %s -> %s (renamed, %d%% similar)%s%s\n", ColorYellow, pair.RenamedFrom, pair.Key, pair.Similarity, ColorReset, objectSourceSuffix(*pair.New))
+		}
+	}
+
+	// Compare objects that exist in both files for modifications
+	for _, pair := range pairs {
+		if pair.Old != nil && pair.New != nil {
+			diffObject(*pair.Old, *pair.New)
+		}
+	}
+}
+
+// objectSourceSuffix returns "  # file:line" for an object read from a file.
+func objectSourceSuffix(obj K8sObject) string {
+	if obj.Source == nil {
+		return ""
+	}
+	return fmt.Sprintf("  %s# %s%s", ColorWhite, obj.Source, ColorReset)
+}
+
+// objectPair links the two versions of the same Kubernetes object.
+// Old is nil for objects that only exist in file2 (additions), and New is nil
+// for objects that only exist in file1 (removals). A renamed or moved object
+// carries its new key in Key and its old key in RenamedFrom.
+type objectPair struct {
+	Key string
+	Old *K8sObject
+	New *K8sObject
+
+	RenamedFrom string // Old key of a renamed object, "" otherwise
+	Similarity  int    // Similarity percentage of a renamed object
+}
+
+// matchObjects pairs up objects from both files by their "Kind/Name" key.
+// This is the shared object-identity step behind every output format.
+//
+// The result is ordered deterministically: objects from file1 in file order
+// (matched or removed), followed by objects that only exist in file2 in file order.
+// If a key appears more than once in a file, the last occurrence wins.
+//
+// Unless rename detection is disabled (opts.NoRenames), removed and added
+// objects are then paired up as renames (see detectRenames).
+func matchObjects(objects1, objects2 []K8sObject) []objectPair {
 	// Create maps for O(1) lookup by kind/name combination
-	map1 := make(map[string]K8sObject)
-	map2 := make(map[string]K8sObject)
+	map1 := make(map[string]*K8sObject)
+	map2 := make(map[string]*K8sObject)
+	var order1, order2 []string
 
 	// Build lookup map for first file's objects
-	for _, obj := range objects1 {
-		key := getObjectKey(obj)
-		map1[key] = obj
+	for i := range objects1 {
+		key := getObjectKey(objects1[i])
+		if _, seen := map1[key]; !seen {
+			order1 = append(order1, key)
+		}
+		map1[key] = &objects1[i]
 	}
 
 	// Build lookup map for second file's objects
-	for _, obj := range objects2 {
-		key := getObjectKey(obj)
-		map2[key] = obj
-	}
-
-	// Identify objects removed (exist in file1 but not file2)
-	for key, obj := range map1 {
-		if _, exists := map2[key]; !exists {
-			fmt.Printf("%s- %s %s (removed)%s\n", ColorRed, obj.Kind, getObjectName(obj), ColorReset)
+	for i := range objects2 {
+		key := getObjectKey(objects2[i])
+		if _, seen := map2[key]; !seen {
+			order2 = append(order2, key)
 		}
+		map2[key] = &objects2[i]
 	}
 
-	// Identify objects added (exist in file2 but not file1)
-	for key, obj := range map2 {
+	var pairs []objectPair
+	for _, key := range order1 {
+		pairs = append(pairs, objectPair{Key: key, Old: map1[key], New: map2[key]})
+	}
+	for _, key := range order2 {
 		if _, exists := map1[key]; !exists {
-			fmt.Printf("%s+ %s %s (added)%s\n", ColorGreen, obj.Kind, getObjectName(obj), ColorReset)
+			pairs = append(pairs, objectPair{Key: key, New: map2[key]})
 		}
 	}
 
-	// Compare objects that exist in both files for modifications
-	for key, obj1 := range map1 {
-		if obj2, exists := map2[key]; exists {
-			diffObject(obj1, obj2)
-		}
+	if !opts.NoRenames {
+		pairs = detectRenames(pairs, opts.RenameThreshold)
 	}
+	return pairs
 }
 
 // getObjectKey creates a unique identifier for a Kubernetes object.
@@ -362,10 +1150,18 @@ func getObjectNamespace(obj K8sObject) string {
 //
 // Output format mimics YAML structure with "---" separators and proper indentation.
 // Uses color coding to distinguish between unchanged and modified sections.
+//
+// By default the output is compact: unchanged sections are left out (metadata is
+// reduced to the name and namespace that identify the object), and changed maps
+// show only opts.Context unchanged sibling fields around each change. With --full
+// every unchanged section is printed in full.
 func diffObject(obj1, obj2 K8sObject) {
 	// Skip output if objects are identical
-	if !reflect.DeepEqual(obj1, obj2) {
+	if !sameObject(obj1, obj2) {
 		fmt.Printf("\n---\n") // YAML document separator
+		if obj1.Source != nil && obj2.Source != nil {
+			fmt.Printf("%s# %s -> %s%s\n", ColorWhite, obj1.Source, obj2.Source, ColorReset)
+		}
 
 		// Compare apiVersion field
 		if obj1.APIVersion == obj2.APIVersion {
@@ -386,37 +1182,57 @@ func diffObject(obj1, obj2 K8sObject) {
 		// Compare metadata section
 		if reflect.DeepEqual(obj1.Metadata, obj2.Metadata) {
 			fmt.Printf("metadata:\n")
-			printYAMLValue("  ", obj1.Metadata, false)
+			if opts.Full {
+				printYAMLValue("  ", obj1.Metadata, false)
+			} else {
+				printIdentity("  ", obj1)
+			}
 		} else {
 			fmt.Printf("%smetadata:%s\n", ColorYellow, ColorReset)
-			diffAnyValue("  ", obj1.Metadata, obj2.Metadata)
+			diffAnyValue("  ", obj1.Metadata, obj2.Metadata, newDiffLocation(obj1, obj2, "metadata"))
 		}
 
 		// Compare data section (ConfigMaps, Secrets)
 		if obj1.Data != nil || obj2.Data != nil {
 			if reflect.DeepEqual(obj1.Data, obj2.Data) {
-				if obj1.Data != nil {
+				if obj1.Data != nil && opts.Full {
 					fmt.Printf("data:\n")
 					printYAMLValue("  ", obj1.Data, false)
 				}
 			} else {
 				fmt.Printf("%sdata:%s\n", ColorYellow, ColorReset)
-				diffAnyValue("  ", obj1.Data, obj2.Data)
+				diffAnyValue("  ", obj1.Data, obj2.Data, newDiffLocation(obj1, obj2, "data"))
 			}
 		}
 
 		// Compare spec section (Pods, Deployments, Services, etc.)
 		if obj1.Spec != nil || obj2.Spec != nil {
 			if reflect.DeepEqual(obj1.Spec, obj2.Spec) {
-				if obj1.Spec != nil {
+				if obj1.Spec != nil && opts.Full {
 					fmt.Printf("spec:\n")
 					printYAMLValue("  ", obj1.Spec, false)
 				}
 			} else {
 				fmt.Printf("%sspec:%s\n", ColorYellow, ColorReset)
-				diffAnyValue("  ", obj1.Spec, obj2.Spec)
+				diffAnyValue("  ", obj1.Spec, obj2.Spec, newDiffLocation(obj1, obj2, "spec"))
 			}
 		}
+
+		// Compare the remaining top-level fields (RBAC rules, Secret type, ...)
+		if !reflect.DeepEqual(obj1.Extra, obj2.Extra) {
+			diffMaps("", obj1.Extra, obj2.Extra, diffLocation{Old: obj1.Source, New: obj2.Source})
+		} else if len(obj1.Extra) > 0 && opts.Full {
+			printYAMLValue("", obj1.Extra, false)
+		}
+	}
+}
+
+// printIdentity prints the metadata fields that identify an object (name and
+// namespace), standing in for unchanged metadata in compact output.
+func printIdentity(indent string, obj K8sObject) {
+	fmt.Printf("%sname: %s\n", indent, getObjectName(obj))
+	if namespace := getObjectNamespace(obj); namespace != "" {
+		fmt.Printf("%snamespace: %s\n", indent, namespace)
 	}
 }
 
@@ -431,38 +1247,130 @@ func diffObject(obj1, obj2 K8sObject) {
 // This function recreates YAML structure for consistent output formatting.
 func printYAMLValue(indent string, value interface{}, isChanged bool) {
 	color := ""
-	reset := ""
 	if isChanged {
 		color = ColorYellow
+	}
+	writeYAMLValue(os.Stdout, indent, value, color)
+}
+
+// writeYAMLValue is the rendering behind printYAMLValue. It writes to w and
+// wraps every line in color, or leaves lines uncolored when color is "".
+// Map keys are written in alphabetical order so output is stable.
+func writeYAMLValue(w io.Writer, indent string, value interface{}, color string) {
+	reset := ""
+	if color != "" {
 		reset = ColorReset
 	}
 
 	switch v := value.(type) {
 	case map[string]interface{}:
 		// Handle nested maps (e.g., metadata.labels, spec.containers)
-		for key, val := range v {
+		for _, key := range sortedKeys(v) {
+			val := v[key]
 			switch val.(type) {
 			case map[string]interface{}, []interface{}:
 				// Complex values get their own line with increased indentation
-				fmt.Printf("%s%s%s:%s\n", indent, color, key, reset)
-				printYAMLValue(indent+"  ", val, isChanged)
+				fmt.Fprintf(w, "%s%s%s:%s\n", indent, color, key, reset)
+				writeYAMLValue(w, indent+"  ", val, color)
 			default:
 				// Simple key-value pairs on one line
-				fmt.Printf("%s%s%s: %v%s\n", indent, color, key, val, reset)
+				fmt.Fprintf(w, "%s%s%s: %v%s\n", indent, color, key, val, reset)
 			}
 		}
 	case []interface{}:
 		// Handle arrays (e.g., containers, volumes, env variables)
 		for _, item := range v {
-			fmt.Printf("%s%s-%s\n", indent, color, reset)
-			printYAMLValue(indent+"  ", item, isChanged)
+			fmt.Fprintf(w, "%s%s-%s\n", indent, color, reset)
+			writeYAMLValue(w, indent+"  ", item, color)
 		}
 	default:
 		// Handle scalar values (strings, numbers, booleans)
-		fmt.Printf("%s%s%v%s\n", indent, color, value, reset)
+		fmt.Fprintf(w, "%s%s%v%s\n", indent, color, value, reset)
 	}
 }
 
+// printObjectContent prints the body of an added or removed object below its
+// summary line, in the given color. The whole document is printed, every
+// top-level field in objectFields order with printYAMLValue's rendering; Secrets
+// are redacted (see redactObject), and the body is cut after opts.ContentLines
+// lines (0 = no limit) with a note of how many were left out.
+func printObjectContent(obj K8sObject, color string) {
+	obj = redactObject(obj)
+
+	var buf bytes.Buffer
+	for _, field := range objectFields(obj) {
+		val, ok := objectField(obj, field)
+		if !ok {
+			continue
+		}
+		if isComposite(val) {
+			fmt.Fprintf(&buf, "%s:\n", field)
+			writeYAMLValue(&buf, "  ", val, "")
+		} else {
+			fmt.Fprintf(&buf, "%s: %v\n", field, val)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	omitted := 0
+	if opts.ContentLines > 0 && len(lines) > opts.ContentLines {
+		omitted = len(lines) - opts.ContentLines
+		lines = lines[:opts.ContentLines]
+	}
+	for _, line := range lines {
+		fmt.Printf("    %s%s%s\n", color, line, ColorReset)
+	}
+	if omitted > 0 {
+		fmt.Printf("    %s... (%d more lines)%s\n", ColorWhite, omitted, ColorReset)
+	}
+}
+
+// diffLocation tracks where the values being compared live in both objects,
+// so that changed lines in the text output can name their source position.
+type diffLocation struct {
+	Path fieldPath
+	Old  *objectSource
+	New  *objectSource
+}
+
+// newDiffLocation starts a location at a top-level section of two objects.
+func newDiffLocation(obj1, obj2 K8sObject, field string) diffLocation {
+	return diffLocation{Path: fieldPath{keySegment(field)}, Old: obj1.Source, New: obj2.Source}
+}
+
+// child returns the location one segment deeper.
+func (l diffLocation) child(seg pathSegment) diffLocation {
+	return diffLocation{Path: l.Path.child(seg), Old: l.Old, New: l.New}
+}
+
+// sourceSuffix returns "  # file:line" for the value at l in the new (newSide)
+// or old object, or "" when the position is unknown.
+func (l diffLocation) sourceSuffix(newSide bool) string {
+	source := l.Old
+	if newSide {
+		source = l.New
+	}
+	pos, ok := source.lookup(l.Path, newSide)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("  %s# %s%s", ColorWhite, pos, ColorReset)
+}
+
+// leafSourceSuffix is sourceSuffix for a modified value, limited to values
+// that are replaced rather than compared further, so that each change is
+// labelled once instead of on every enclosing key.
+func (l diffLocation) leafSourceSuffix(val1, val2 interface{}) string {
+	_, maps1 := val1.(map[string]interface{})
+	_, maps2 := val2.(map[string]interface{})
+	_, slices1 := val1.([]interface{})
+	_, slices2 := val2.([]interface{})
+	if (maps1 && maps2) || (slices1 && slices2) {
+		return ""
+	}
+	return l.sourceSuffix(true)
+}
+
 // diffAnyValue is the core recursive comparison function that handles any Go value type.
 // It dispatches to specialized diff functions based on the value type.
 //
@@ -472,12 +1380,12 @@ func printYAMLValue(indent string, value interface{}, isChanged bool) {
 //   - Other types: Direct value comparison with ~~/~> format for changes
 //
 // This function is the heart of the semantic diff algorithm.
-func diffAnyValue(indent string, val1, val2 interface{}) {
+func diffAnyValue(indent string, val1, val2 interface{}, loc diffLocation) {
 	switch v1 := val1.(type) {
 	case map[string]interface{}:
 		if v2, ok := val2.(map[string]interface{}); ok {
 			// Both values are maps - compare them structurally
-			diffMaps(indent, v1, v2)
+			diffMaps(indent, v1, v2, loc)
 		} else {
 			// Type mismatch - show as complete replacement
 			fmt.Printf("%s%s~~ %s%s\n", indent, ColorYellow, formatValue(val1), ColorReset)
@@ -487,9 +1395,9 @@ func diffAnyValue(indent string, val1, val2 interface{}) {
 		if v2, ok := val2.([]interface{}); ok {
 			// Both values are arrays - compare them element-wise
 			if isContainerArray(v1) && isContainerArray(v2) {
-				diffContainerArrays(indent, v1, v2)
+				diffContainerArrays(indent, v1, v2, loc)
 			} else {
-				diffSlices(indent, v1, v2)
+				diffSlices(indent, v1, v2, loc)
 			}
 		} else {
 			// Type mismatch - show as complete replacement
@@ -498,11 +1406,133 @@ func diffAnyValue(indent string, val1, val2 interface{}) {
 		}
 	default:
 		// Scalar values (strings, numbers, booleans) - direct comparison
-		if !reflect.DeepEqual(val1, val2) {
-			fmt.Printf("%s%s~~ %s%s\n", indent, ColorYellow, formatValue(val1), ColorReset)
-			fmt.Printf("%s%s~> %s%s\n", indent, ColorYellow, formatValue(val2), ColorReset)
+		if reflect.DeepEqual(val1, val2) {
+			return
+		}
+		if opts.Lenient && scalarsEquivalent(val1, val2) {
+			// Same meaning, different representation - flag but don't alarm
+			fmt.Printf("%s%s~= %s -> %s (type-only change)%s\n", indent, ColorWhite, formatValue(val1), formatValue(val2), ColorReset)
+			return
+		}
+		fmt.Printf("%s%s~~ %s%s\n", indent, ColorYellow, formatValue(val1), ColorReset)
+		fmt.Printf("%s%s~> %s%s\n", indent, ColorYellow, formatValue(val2), ColorReset)
+	}
+}
+
+// scalarsEquivalent reports whether two differing scalar values carry the same
+// meaning despite being represented differently. This smooths over YAML 1.1 vs 1.2
+// quirks and templating artifacts that turn numbers and booleans into strings.
+//
+// Recognized equivalences:
+//   - Numbers: "8080" vs 8080, 1 vs 1.0; two integers must be exactly equal
+//   - Booleans: "true" vs true, and the YAML 1.1 spellings yes/no and on/off
+//   - Durations: 30s vs 30000ms vs 0.5m (Go duration syntax)
+//
+// Only used when lenient comparison is enabled with --lenient.
+func scalarsEquivalent(val1, val2 interface{}) bool {
+	// Integers are compared exactly; float64 cannot tell apart integers above 2^53
+	if i1, ok := scalarInteger(val1); ok {
+		if i2, ok := scalarInteger(val2); ok {
+			return i1.Cmp(i2) == 0
+		}
+	}
+
+	if n1, ok := scalarNumber(val1); ok {
+		if n2, ok := scalarNumber(val2); ok {
+			return n1 == n2
+		}
+	}
+
+	if b1, ok := scalarBool(val1); ok {
+		if b2, ok := scalarBool(val2); ok {
+			return b1 == b2
+		}
+	}
+
+	if d1, ok := scalarDuration(val1); ok {
+		if d2, ok := scalarDuration(val2); ok {
+			return d1 == d2
+		}
+	}
+
+	return false
+}
+
+// scalarInteger interprets a scalar as an integer, accepting decimal integer
+// strings of any size.
+func scalarInteger(val interface{}) (*big.Int, bool) {
+	switch v := val.(type) {
+	case int:
+		return big.NewInt(int64(v)), true
+	case int64:
+		return big.NewInt(v), true
+	case uint64:
+		return new(big.Int).SetUint64(v), true
+	case string:
+		return new(big.Int).SetString(strings.TrimSpace(v), 10)
+	}
+	return nil, false
+}
+
+// scalarNumber interprets a scalar as a number, accepting numeric strings.
+// It is used for values that are not both integers (see scalarInteger).
+func scalarNumber(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, !math.IsNaN(v)
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || math.IsNaN(f) {
+			return 0, false
+		}
+		return f, true
+	}
+	return 0, false
+}
+
+// scalarBool interprets a scalar as a boolean, accepting the string spellings
+// true/false, yes/no and on/off that YAML 1.1 parsers commonly treat as
+// booleans. The single letters y and n are left alone: they are far more often
+// meant literally.
+func scalarBool(val interface{}) (bool, bool) {
+	switch v := val.(type) {
+	case bool:
+		return v, true
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "true", "yes", "on":
+			return true, true
+		case "false", "no", "off":
+			return false, true
 		}
 	}
+	return false, false
+}
+
+// scalarDuration interprets a string scalar as a Go-style duration (e.g. "30s", "1m30s").
+// Strings without a trailing unit, including "0", are rejected so that plain integers are
+// left to scalarInteger.
+func scalarDuration(val interface{}) (time.Duration, bool) {
+	s, ok := val.(string)
+	if !ok {
+		return 0, false
+	}
+	s = strings.TrimSpace(s)
+	// time.ParseDuration accepts a unitless "0"; require a unit suffix.
+	if s == "" || s[len(s)-1] >= '0' && s[len(s)-1] <= '9' {
+		return 0, false
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, false
+	}
+	return d, true
 }
 
 // diffMaps compares two maps key by key, identifying additions, removals, and modifications.
@@ -518,33 +1548,83 @@ func diffAnyValue(indent string, val1, val2 interface{}) {
 //   - "~": Key exists in both but values differ (modification)
 //
 // This handles nested structures like metadata.labels, spec.containers, etc.
-func diffMaps(indent string, map1, map2 map[string]interface{}) {
+//
+// Keys are visited in alphabetical order. In compact mode (the default) up to
+// opts.Context unchanged keys before and after each change are printed as
+// context, and "..." marks the unchanged keys that were skipped.
+func diffMaps(indent string, map1, map2 map[string]interface{}, loc diffLocation) {
 	// Build union of all keys from both maps
-	allKeys := make(map[string]bool)
-	for key := range map1 {
-		allKeys[key] = true
+	allKeys := unionKeys(map1, map2)
+	unchanged := func(key string) bool {
+		val1, exists1 := map1[key]
+		val2, exists2 := map2[key]
+		return exists1 && exists2 && reflect.DeepEqual(val1, val2)
 	}
-	for key := range map2 {
-		allKeys[key] = true
+
+	// Decide which unchanged keys are shown as context around the changes
+	showContext := make([]bool, len(allKeys))
+	if !opts.Full {
+		for i, key := range allKeys {
+			if unchanged(key) {
+				continue
+			}
+			for j := max(0, i-opts.Context); j <= min(len(allKeys)-1, i+opts.Context); j++ {
+				showContext[j] = true
+			}
+		}
 	}
 
 	// Compare each key's presence and value
-	for key := range allKeys {
+	skipped := false
+	for i, key := range allKeys {
 		val1, exists1 := map1[key]
 		val2, exists2 := map2[key]
 
-		if !exists1 {
+		if unchanged(key) {
+			if opts.Full {
+				continue
+			}
+			if !showContext[i] {
+				skipped = true
+				continue
+			}
+		}
+		if skipped {
+			fmt.Printf("%s%s...%s\n", indent, ColorWhite, ColorReset)
+			skipped = false
+		}
+
+		if unchanged(key) {
+			// Unchanged key shown as context
+			fmt.Printf("%s%s  %s: %s%s\n", indent, ColorWhite, key, contextValue(val1), ColorReset)
+		} else if !exists1 {
 			// Key was added in map2
-			fmt.Printf("%s%s+ %s: %s%s\n", indent, ColorGreen, key, formatValue(val2), ColorReset)
+			fmt.Printf("%s%s+ %s: %s%s%s\n", indent, ColorGreen, key, formatValue(val2), ColorReset, loc.child(keySegment(key)).sourceSuffix(true))
 		} else if !exists2 {
 			// Key was removed from map1
-			fmt.Printf("%s%s- %s: %s%s\n", indent, ColorRed, key, formatValue(val1), ColorReset)
+			fmt.Printf("%s%s- %s: %s%s%s\n", indent, ColorRed, key, formatValue(val1), ColorReset, loc.child(keySegment(key)).sourceSuffix(false))
 		} else if !reflect.DeepEqual(val1, val2) {
 			// Key exists in both but values differ
-			fmt.Printf("%s%s~ %s:%s\n", indent, ColorYellow, key, ColorReset)
-			diffAnyValue(indent+"  ", val1, val2)
+			child := loc.child(keySegment(key))
+			fmt.Printf("%s%s~ %s:%s%s\n", indent, ColorYellow, key, ColorReset, child.leafSourceSuffix(val1, val2))
+			diffAnyValue(indent+"  ", val1, val2, child)
 		}
 	}
+	if skipped {
+		fmt.Printf("%s%s...%s\n", indent, ColorWhite, ColorReset)
+	}
+}
+
+// contextValue formats an unchanged value shown as context in compact output.
+// Nested maps and lists are collapsed to {...} and [...] so context stays one line.
+func contextValue(val interface{}) string {
+	switch val.(type) {
+	case map[string]interface{}:
+		return "{...}"
+	case []interface{}:
+		return "[...]"
+	}
+	return formatValue(val)
 }
 
 // diffSlices compares two slices element by element.
@@ -559,7 +1639,7 @@ func diffMaps(indent string, map1, map2 map[string]interface{}) {
 //
 // Limitation: Currently optimized for simple cases. Could be enhanced with
 // LCS (Longest Common Subsequence) algorithm for better array diff visualization.
-func diffSlices(indent string, slice1, slice2 []interface{}) {
+func diffSlices(indent string, slice1, slice2 []interface{}, loc diffLocation) {
 	// For arrays of different lengths, show complete replacement
 	// This handles cases where containers are added/removed
 	if len(slice1) != len(slice2) {
@@ -571,8 +1651,9 @@ func diffSlices(indent string, slice1, slice2 []interface{}) {
 	// For same-length arrays, compare element by element
 	for i := 0; i < len(slice1); i++ {
 		if !reflect.DeepEqual(slice1[i], slice2[i]) {
-			fmt.Printf("%s%s[%d]:%s\n", indent, ColorYellow, i, ColorReset)
-			diffAnyValue(indent+"  ", slice1[i], slice2[i])
+			child := loc.child(itemSegment("", i, i))
+			fmt.Printf("%s%s[%d]:%s%s\n", indent, ColorYellow, i, ColorReset, child.leafSourceSuffix(slice1[i], slice2[i]))
+			diffAnyValue(indent+"  ", slice1[i], slice2[i], child)
 		}
 	}
 }
@@ -604,6 +1685,36 @@ func formatValue(val interface{}) string {
 	return yamlStr
 }
 
+// sortedKeys returns the keys of a map in alphabetical order.
+// Used wherever output must be stable from run to run.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// containersByName indexes a container array by container name.
+// Returns the lookup map plus the names in array order, so callers can walk
+// containers deterministically. Entries without a string name are skipped.
+func containersByName(slice []interface{}) (map[string]interface{}, []string) {
+	containers := make(map[string]interface{})
+	var names []string
+	for _, container := range slice {
+		if c, ok := container.(map[string]interface{}); ok {
+			if name, ok := c["name"].(string); ok {
+				if _, seen := containers[name]; !seen {
+					names = append(names, name)
+				}
+				containers[name] = container
+			}
+		}
+	}
+	return containers, names
+}
+
 // isContainerArray checks if we're dealing with a Kubernetes containers array
 // by examining the structure for container-like objects with name and image fields.
 func isContainerArray(slice []interface{}) bool {
@@ -627,45 +1738,19 @@ func isContainerArray(slice []interface{}) bool {
 //
 // The red exclamation mark (!) indicator shows when the container array is "tainted"
 // by additions or removals, helping users quickly identify structural changes.
-func diffContainerArrays(indent string, slice1, slice2 []interface{}) {
+func diffContainerArrays(indent string, slice1, slice2 []interface{}, loc diffLocation) {
 	// Build maps keyed by container name for semantic comparison
-	containers1 := make(map[string]interface{})
-	containers2 := make(map[string]interface{})
-
-	// Extract containers by name from first array
-	for _, container := range slice1 {
-		if c, ok := container.(map[string]interface{}); ok {
-			if name, ok := c["name"].(string); ok {
-				containers1[name] = container
-			}
-		}
-	}
-
-	// Extract containers by name from second array
-	for _, container := range slice2 {
-		if c, ok := container.(map[string]interface{}); ok {
-			if name, ok := c["name"].(string); ok {
-				containers2[name] = container
-			}
-		}
-	}
-
-	// Find all container names across both arrays
-	allNames := make(map[string]bool)
-	for name := range containers1 {
-		allNames[name] = true
-	}
-	for name := range containers2 {
-		allNames[name] = true
-	}
+	containers1, names1 := containersByName(slice1)
+	containers2, names2 := containersByName(slice2)
 
 	// Check if array is "tainted" by additions or removals
 	hasTaint := len(containers1) != len(containers2)
 
-	// Compare containers by name
-	for name := range allNames {
+	// Compare containers by name, in old array order followed by added containers
+	for _, name := range appendMissing(names1, names2) {
 		container1, exists1 := containers1[name]
 		container2, exists2 := containers2[name]
+		child := loc.child(itemSegment(name, indexOfContainer(slice1, name), indexOfContainer(slice2, name)))
 
 		if !exists1 {
 			// Container added - mark as tainted
@@ -673,18 +1758,18 @@ func diffContainerArrays(indent string, slice1, slice2 []interface{}) {
 			if hasTaint {
 				taintIndicator = fmt.Sprintf("%s! %s", ColorRed, ColorReset)
 			}
-			fmt.Printf("%s%s+ %scontainer '%s': %s%s\n", indent, ColorGreen, taintIndicator, name, formatValue(container2), ColorReset)
+			fmt.Printf("%s%s+ %scontainer '%s': %s%s%s\n", indent, ColorGreen, taintIndicator, name, formatValue(container2), ColorReset, child.sourceSuffix(true))
 		} else if !exists2 {
 			// Container removed - mark as tainted
 			taintIndicator := ""
 			if hasTaint {
 				taintIndicator = fmt.Sprintf("%s! %s", ColorRed, ColorReset)
 			}
-			fmt.Printf("%s%s- %scontainer '%s': %s%s\n", indent, ColorRed, taintIndicator, name, formatValue(container1), ColorReset)
+			fmt.Printf("%s%s- %scontainer '%s': %s%s%s\n", indent, ColorRed, taintIndicator, name, formatValue(container1), ColorReset, child.sourceSuffix(false))
 		} else if !reflect.DeepEqual(container1, container2) {
 			// Container modified (no taint indicator for modifications)
 			fmt.Printf("%s%s~ container '%s':%s\n", indent, ColorYellow, name, ColorReset)
-			diffAnyValue(indent+"  ", container1, container2)
+			diffAnyValue(indent+"  ", container1, container2, child)
 		}
 	}
 }
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// mappingRule replaces From with To when carrying an object from the first
+// environment over to the second.
+type mappingRule struct {
+	From string
+	To   string
+}
+
+// envRules describe the differences expected between two environments.
+//
+//   - Namespaces maps whole namespace names ("staging" -> "prod")
+//   - Names replaces parts of object names ("-staging" -> "-prod")
+//   - Values replaces parts of every other string value, such as hostnames
+//     ("staging.example.com" -> "example.com")
+//   - Ignore lists field paths whose differences are expected ("spec.replicas");
+//     a path also covers everything below it
+type envRules struct {
+	Namespaces []mappingRule
+	Names      []mappingRule
+	Values     []mappingRule
+	Ignore     []string
+}
+
+// envConfig is the file format of --env-config.
+type envConfig struct {
+	Namespaces map[string]string `yaml:"namespaces"`
+	Names      map[string]string `yaml:"names"`
+	Values     map[string]string `yaml:"values"`
+	Ignore     []string          `yaml:"ignore"`
+}
+
+// active reports whether any rule is set.
+func (r envRules) active() bool {
+	return len(r.Namespaces)+len(r.Names)+len(r.Values)+len(r.Ignore) > 0
+}
+
+// runEnvCommand implements "k8s-diff env <side1> <side2>".
+//
+// Both sides are read like the kustomize command reads them. Every object of
+// the first environment is then carried over to the second one by the mapping
+// rules, so that "Deployment/staging/api-staging" pairs up with
+// "Deployment/prod/api-prod" and a staging hostname compares equal to the
+// prod one. What remains after the mapping and the ignored fields is reported
+// as environment drift, per object and field.
+func runEnvCommand(args []string) {
+	if len(args) != 2 {
+		fmt.Fprintf(os.Stderr, "Error: env expects exactly 2 arguments (<side1> <side2>), got %d\n\n", len(args))
+		fmt.Print(helpText)
+		os.Exit(1)
+	}
+	if opts.Output != "text" || opts.Summary != "" {
+		fmt.Fprintf(os.Stderr, "Error: env only supports the default text output\n")
+		os.Exit(1)
+	}
+
+	rules := opts.Env
+	if opts.EnvConfig != "" {
+		if err := checkFileExists(opts.EnvConfig); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		config, err := readEnvConfig(opts.EnvConfig)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", opts.EnvConfig, err)
+			os.Exit(1)
+		}
+		rules = rules.merge(config)
+	}
+
+	var sides [2][]K8sObject
+	for i, path := range args {
+		if err := checkFileExists(path); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		objects, err := readKustomizeSide(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error building %s: %v\n", path, err)
+			os.Exit(1)
+		}
+		sides[i] = objects
+	}
+
+	// Remember the original identity of each carried-over object for the report
+	origins := make(map[string]string)
+	for i := range sides[0] {
+		original := getObjectKey(sides[0][i])
+		rules.translate(&sides[0][i])
+		origins[getObjectKey(sides[0][i])] = original
+	}
+
+	label1, label2 := envLabels(args[0], args[1])
+	writeEnvDrift(os.Stdout, label1, label2, rules, origins, sides[0], sides[1])
+}
+
+// readEnvConfig loads mapping rules from a YAML file:
+//
+//	namespaces: {staging: prod}
+//	names: {-staging: -prod}
+//	values: {staging.example.com: example.com}
+//	ignore: [spec.replicas]
+func readEnvConfig(filename string) (envRules, error) {
+	var rules envRules
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return rules, err
+	}
+	var config envConfig
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return rules, fmt.Errorf("invalid env config: %v", err)
+	}
+
+	toRules := func(m map[string]string) []mappingRule {
+		var list []mappingRule
+		for from, to := range m {
+			list = append(list, mappingRule{From: from, To: to})
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].From < list[j].From })
+		return list
+	}
+	rules.Namespaces = toRules(config.Namespaces)
+	rules.Names = toRules(config.Names)
+	rules.Values = toRules(config.Values)
+	rules.Ignore = config.Ignore
+	return rules, nil
+}
+
+// merge returns the rules of r followed by those of other.
+func (r envRules) merge(other envRules) envRules {
+	return envRules{
+		Namespaces: append(append([]mappingRule(nil), r.Namespaces...), other.Namespaces...),
+		Names:      append(append([]mappingRule(nil), r.Names...), other.Names...),
+		Values:     append(append([]mappingRule(nil), r.Values...), other.Values...),
+		Ignore:     append(append([]string(nil), r.Ignore...), other.Ignore...),
+	}
+}
+
+// translate carries an object of the first environment over to the second.
+// The namespace is mapped as a whole; names and other string values, in every
+// top-level field, are rewritten in one pass where the longest matching rule wins, so rules never
+// apply to each other's output.
+func (r envRules) translate(obj *K8sObject) {
+	if namespace, ok := obj.Metadata["namespace"].(string); ok {
+		for _, rule := range r.Namespaces {
+			if namespace == rule.From {
+				obj.Metadata["namespace"] = rule.To
+				break
+			}
+		}
+	}
+
+	names := newRuleReplacer(r.Names)
+	obj.Metadata["name"] = names.Replace(getObjectName(*obj))
+
+	values := newRuleReplacer(r.Values)
+	var rewrite func(val interface{}) interface{}
+	rewrite = func(val interface{}) interface{} {
+		switch v := val.(type) {
+		case string:
+			return values.Replace(v)
+		case map[string]interface{}:
+			for key, item := range v {
+				v[key] = rewrite(item)
+			}
+		case []interface{}:
+			for i, item := range v {
+				v[i] = rewrite(item)
+			}
+		}
+		return val
+	}
+	for key, val := range obj.Metadata {
+		if key != "name" && key != "namespace" {
+			obj.Metadata[key] = rewrite(val)
+		}
+	}
+	rewrite(obj.Data)
+	rewrite(obj.Spec)
+	rewrite(obj.Extra)
+}
+
+// newRuleReplacer builds a single-pass replacer that prefers longer matches.
+func newRuleReplacer(rules []mappingRule) *strings.Replacer {
+	sorted := append([]mappingRule(nil), rules...)
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i].From) > len(sorted[j].From) })
+	var pairs []string
+	for _, rule := range sorted {
+		pairs = append(pairs, rule.From, rule.To)
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+// ignores reports whether a field path is covered by an ignore rule.
+func (r envRules) ignores(path fieldPath) bool {
+	text := path.String()
+	for _, ignored := range r.Ignore {
+		if rest, ok := strings.CutPrefix(text, ignored); ok && (rest == "" || rest[0] == '.' || rest[0] == '[') {
+			return true
+		}
+	}
+	return false
+}
+
+// String lists the rules on one line for the report header.
+func (r envRules) String() string {
+	var parts []string
+	for _, rule := range r.Namespaces {
+		parts = append(parts, fmt.Sprintf("namespace %s -> %s", rule.From, rule.To))
+	}
+	for _, rule := range r.Names {
+		parts = append(parts, fmt.Sprintf("name %q -> %q", rule.From, rule.To))
+	}
+	for _, rule := range r.Values {
+		parts = append(parts, fmt.Sprintf("value %q -> %q", rule.From, rule.To))
+	}
+	for _, path := range r.Ignore {
+		parts = append(parts, "ignore "+path)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// envLabels names the two environments after their directory or file names,
+// falling back to the full paths when those are the same.
+func envLabels(path1, path2 string) (string, string) {
+	label := func(path string) string {
+		return strings.TrimSuffix(filepath.Base(filepath.Clean(path)), filepath.Ext(path))
+	}
+	if label1, label2 := label(path1), label(path2); label1 != label2 {
+		return label1, label2
+	}
+	return path1, path2
+}
+
+// writeEnvDrift prints the environment drift report. Objects are listed in
+// match order; a drifted object lists each differing field with the value in
+// both environments:
+//
+//	Deployment/prod/api-prod (staging: Deployment/staging/api-staging)
+//	  spec.template.spec.containers[name=api].image  # prod/deploy.yaml:21
+//	    staging: api:1.4
+//	    prod:    api:1.5
+func writeEnvDrift(w io.Writer, label1, label2 string, rules envRules, origins map[string]string, objects1, objects2 []K8sObject) {
+	fmt.Fprintf(w, "Environment drift from %s to %s\n", label1, label2)
+	if rules.active() {
+		fmt.Fprintf(w, "%sRules: %s%s\n", ColorWhite, rules, ColorReset)
+	}
+
+	width := max(len(label1), len(label2)) + 1
+	drifted, expected, only1, only2 := 0, 0, 0, 0
+
+	for _, pair := range matchObjects(objects1, objects2) {
+		original, origin := pair.Key, ""
+		if pair.Old != nil {
+			if original = origins[getObjectKey(*pair.Old)]; original != pair.Key {
+				origin = fmt.Sprintf(" (%s: %s)", label1, original)
+			}
+		}
+
+		switch pairStatus(pair) {
+		case "removed":
+			only1++
+			fmt.Fprintf(w, "\n%s- %s only in %s%s%s\n", ColorRed, original, label1, ColorReset, objectSourceSuffix(*pair.Old))
+			continue
+		case "added":
+			only2++
+			fmt.Fprintf(w, "\n%s+ %s only in %s%s%s\n", ColorGreen, pair.Key, label2, ColorReset, objectSourceSuffix(*pair.New))
+			continue
+		case "unchanged":
+			expected++
+			continue
+		}
+
+		var fields []fieldChange
+		for _, change := range objectChanges(*pair.Old, *pair.New) {
+			for _, leaf := range leafChanges(change) {
+				if !rules.ignores(leaf.Path) {
+					fields = append(fields, leaf)
+				}
+			}
+		}
+		if len(fields) == 0 {
+			expected++
+			continue
+		}
+
+		drifted++
+		fmt.Fprintf(w, "\n%s~ %s%s%s\n", ColorYellow, pair.Key, origin, ColorReset)
+		for _, field := range fields {
+			source := ""
+			if pos, ok := changeSource(pair, field); ok {
+				source = fmt.Sprintf("  %s# %s%s", ColorWhite, pos, ColorReset)
+			}
+			oldText, newText := flatValue(field.Old), flatValue(field.New)
+			switch {
+			case isSensitivePath(*pair.New, field.Path):
+				oldText, newText = redactedValue, redactedValue
+			case field.Type == changeAdded:
+				oldText = "<none>"
+			case field.Type == changeRemoved:
+				newText = "<none>"
+			}
+			fmt.Fprintf(w, "  %s%s\n", field.Path, source)
+			fmt.Fprintf(w, "    %s%-*s %s%s\n", ColorRed, width, label1+":", oldText, ColorReset)
+			fmt.Fprintf(w, "    %s%-*s %s%s\n", ColorGreen, width, label2+":", newText, ColorReset)
+		}
+	}
+
+	fmt.Fprintf(w, "\n%d object(s) drifted, %d as expected, %d only in %s, %d only in %s\n", drifted, expected, only1, label1, only2, label2)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// gitConfigSnippet is printed by "k8s-diff gitconfig". The diff driver is only
+// attached to YAML files through .gitattributes, so git keeps its own diff for
+// everything else (GIT_EXTERNAL_DIFF would hand every file to k8s-diff).
+const gitConfigSnippet = `# .gitattributes - route YAML files through the k8s diff driver; narrow the
+# patterns to the manifest directories (e.g. deploy/**/*.yaml diff=k8s) so
+# kustomization.yaml, Helm values and CI workflows keep git's own diff
+*.yaml diff=k8s
+*.yml  diff=k8s
+
+# .git/config or ~/.gitconfig - "git diff" runs k8s-diff for those files;
+# options such as --stat or -o flat can be added to the command
+[diff "k8s"]
+	command = k8s-diff
+
+# "git difftool -t k8s" runs k8s-diff on each changed file
+[difftool "k8s"]
+	cmd = k8s-diff "$LOCAL" "$REMOTE"
+`
+
+// isExternalDiffArgs reports whether the positional arguments follow the
+// convention git uses to call GIT_EXTERNAL_DIFF and diff drivers:
+//
+//	path old-file old-hex old-mode new-file new-hex new-mode [new-path xfrm-msg]
+//
+// The two extra arguments are passed for renamed and copied files. No other
+// invocation of k8s-diff takes 7 or 9 positional arguments.
+func isExternalDiffArgs(args []string) bool {
+	return len(args) == 7 || len(args) == 9
+}
+
+// runExternalDiff diffs one file on behalf of git. old-file and new-file are
+// temporary files or /dev/null when the file was added or deleted, so an
+// added or deleted manifest shows all of its objects as added or removed.
+// Objects are labelled a/<path> and b/<path> like git's own diff, and the
+// text output starts with a header naming the file, since git prints the
+// output of every changed file in a row. Files that do not parse as manifests
+// get a plain text diff instead of an error, which git would treat as fatal.
+func runExternalDiff(args []string) {
+	oldPath, newPath := args[0], args[0]
+	if len(args) == 9 {
+		newPath = args[7]
+	}
+
+	content1, err := readExternalDiffFile(args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading a/%s: %v\n", oldPath, err)
+		os.Exit(1)
+	}
+	content2, err := readExternalDiffFile(args[4])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading b/%s: %v\n", newPath, err)
+		os.Exit(1)
+	}
+
+	// YAML that is not a manifest (kustomization.yaml, Helm values, CI
+	// workflows) still gets a diff: failing here would abort all of git diff
+	objects1, err1 := parseManifestData("a/"+oldPath, content1)
+	objects2, err2 := parseManifestData("b/"+newPath, content2)
+	if err1 != nil || err2 != nil {
+		writeTextDiff(os.Stdout, externalDiffName("a/", oldPath, args[1]), externalDiffName("b/", newPath, args[4]), content1, content2)
+		return
+	}
+
+	if opts.Output == "text" {
+		fmt.Printf("%sk8s-diff a/%s b/%s%s\n", ColorWhite, oldPath, newPath, ColorReset)
+	}
+	writeDiff("a/"+oldPath, "b/"+newPath, objects1, objects2)
+}
+
+// readExternalDiffFile reads one side of an external diff call.
+// /dev/null is an empty side.
+func readExternalDiffFile(file string) ([]byte, error) {
+	if file == os.DevNull {
+		return nil, nil
+	}
+	return os.ReadFile(file)
+}
+
+// externalDiffName returns the name of one side in a text diff header:
+// prefix+path, or /dev/null for a missing side.
+func externalDiffName(prefix, path, file string) string {
+	if file == os.DevNull {
+		return os.DevNull
+	}
+	return prefix + path
+}
+
+// runGitConfigCommand implements "k8s-diff gitconfig", printing the
+// .gitattributes and git configuration that enable k8s-diff for git diff and
+// git difftool.
+func runGitConfigCommand(args []string) {
+	if len(args) != 0 {
+		fmt.Fprintf(os.Stderr, "Error: gitconfig takes no arguments, got %d\n\n", len(args))
+		fmt.Print(helpText)
+		os.Exit(1)
+	}
+	fmt.Print(gitConfigSnippet)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// writeFlat prints one line per leaf change, prefixed with the object identity,
+// so the output can be grepped and reviewed without any surrounding structure:
+//
+//	Deployment/prod/api spec.replicas: 3 -> 4
+//	Deployment/prod/api spec.template.spec.containers[name=app].image: nginx:1.21 -> nginx:1.22
+//	Deployment/prod/api metadata.labels.team: <none> -> payments
+//	ConfigMap/prod/flags: added
+//	ConfigMap/prod/app-v2: renamed from ConfigMap/prod/app, 80% similar
+//
+// Each line starts with the "file:line: " source position of the change (the
+// old file for removals, the new file otherwise), in the format compilers use,
+// so editors can jump to it.
+//
+// Array items are addressed by name where the matching logic identifies them by
+// name (containers) and by index otherwise. Added and removed maps and lists are
+// expanded into their leaves; arrays that changed length are reported as one
+// whole-value change. Secret data and last-applied configurations are redacted
+// (see isSensitivePath).
+func writeFlat(w io.Writer, objects1, objects2 []K8sObject) {
+	for _, pair := range matchObjects(objects1, objects2) {
+		switch pairStatus(pair) {
+		case "added", "removed":
+			pos, ok := pairSource(pair)
+			fmt.Fprintf(w, "%s%s: %s\n", flatSourcePrefix(pos, ok), pair.Key, pairStatus(pair))
+		case "modified":
+			if note := renameNote(pair); note != "" {
+				pos, ok := pairSource(pair)
+				fmt.Fprintf(w, "%s%s: %s\n", flatSourcePrefix(pos, ok), pair.Key, note)
+			}
+			for _, leaf := range objectLeafChanges(*pair.Old, *pair.New) {
+				pos, ok := changeSource(pair, leaf)
+				fmt.Fprintf(w, "%s%s %s\n", flatSourcePrefix(pos, ok), pair.Key, formatFlatChange(leaf, isSensitivePath(*pair.New, leaf.Path)))
+			}
+		}
+	}
+}
+
+// flatSourcePrefix returns "file:line: " for a known position, or "".
+func flatSourcePrefix(pos sourcePosition, ok bool) string {
+	if !ok {
+		return ""
+	}
+	return pos.String() + ": "
+}
+
+// objectLeafChanges lists the changes between two versions of an object like
+// objectChanges, with added and removed maps and lists expanded into their
+// leaves (see leafChanges).
+func objectLeafChanges(obj1, obj2 K8sObject) []fieldChange {
+	var leaves []fieldChange
+	for _, change := range objectChanges(obj1, obj2) {
+		leaves = append(leaves, leafChanges(change)...)
+	}
+	return leaves
+}
+
+// leafChanges expands an added or removed map or list into one change per leaf
+// value. Other changes are returned as they are.
+func leafChanges(change fieldChange) []fieldChange {
+	var val interface{}
+	switch change.Type {
+	case changeAdded:
+		val = change.New
+	case changeRemoved:
+		val = change.Old
+	default:
+		return []fieldChange{change}
+	}
+
+	var leaves []fieldChange
+	switch v := val.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			leaves = append(leaves, leafChanges(childChange(change, keySegment(key), v[key]))...)
+		}
+	case []interface{}:
+		_, names := containersByName(v)
+		byName := isContainerArray(v) && len(names) == len(v)
+		for i, item := range v {
+			name := ""
+			if byName {
+				name = names[i]
+			}
+			seg := itemSegment(name, i, -1)
+			if change.Type == changeAdded {
+				seg = itemSegment(name, -1, i)
+			}
+			leaves = append(leaves, leafChanges(childChange(change, seg, item))...)
+		}
+	}
+	if len(leaves) == 0 {
+		return []fieldChange{change} // Scalars and empty maps or lists are leaves themselves
+	}
+	return leaves
+}
+
+// childChange returns the change of one nested value of an added or removed value.
+func childChange(change fieldChange, seg pathSegment, val interface{}) fieldChange {
+	child := fieldChange{Path: change.Path.child(seg), Type: change.Type}
+	if change.Type == changeAdded {
+		child.New = val
+	} else {
+		child.Old = val
+	}
+	return child
+}
+
+// formatFlatChange renders a leaf change as "path: old -> new", using <none>
+// for the missing side of additions and removals.
+func formatFlatChange(change fieldChange, redact bool) string {
+	oldText, newText := flatValue(change.Old), flatValue(change.New)
+	if redact {
+		oldText, newText = redactedValue, redactedValue
+	}
+
+	switch change.Type {
+	case changeAdded:
+		oldText = "<none>"
+	case changeRemoved:
+		newText = "<none>"
+	case changeTypeOnly:
+		return fmt.Sprintf("%s: %s -> %s (type-only change)", change.Path, oldText, newText)
+	}
+	return fmt.Sprintf("%s: %s -> %s", change.Path, oldText, newText)
+}
+
+// flatValue formats a value on a single line. Maps and lists are written as
+// compact JSON, since formatValue would cut multi-line YAML short.
+func flatValue(val interface{}) string {
+	if isComposite(val) {
+		if encoded, err := json.Marshal(val); err == nil {
+			return string(encoded)
+		}
+	}
+	return formatValue(val)
+}
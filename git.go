@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// runGitCommand implements "k8s-diff git <rev1> [<rev2>] -- <path>...".
+//
+// The manifests under each path are read at <rev1> and <rev2> straight from
+// the object database of the repository containing the working directory, so
+// nothing is checked out and the working tree is left alone. Without <rev2>
+// the working tree itself is the new side. Paths may be files or directories
+// and are resolved relative to the working directory like in git; a path that
+// does not exist at a revision contributes no objects, so manifests added or
+// deleted between the revisions show up as added or removed objects.
+func runGitCommand(args []string) {
+	sep := indexOf(args, "--")
+	if sep < 0 {
+		fmt.Fprintf(os.Stderr, "Error: git expects '--' between the revisions and the manifest paths\n\n")
+		fmt.Print(helpText)
+		os.Exit(1)
+	}
+	revs, paths := args[:sep], args[sep+1:]
+	if len(revs) < 1 || len(revs) > 2 || len(paths) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: git expects <rev1> [<rev2>] -- <path>..., got %d revision(s) and %d path(s)\n\n", len(revs), len(paths))
+		fmt.Print(helpText)
+		os.Exit(1)
+	}
+
+	objects1, err := readRevisionObjects(revs[0], paths)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", revs[0], err)
+		os.Exit(1)
+	}
+
+	label2 := "working tree"
+	var objects2 []K8sObject
+	if len(revs) == 2 {
+		label2 = revs[1]
+		objects2, err = readRevisionObjects(revs[1], paths)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", revs[1], err)
+			os.Exit(1)
+		}
+	} else {
+		for _, path := range paths {
+			if err := checkFileExists(path); err != nil {
+				continue // Deleted in the working tree
+			}
+			pathObjects, err := parseK8sObjects(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error parsing %s: %v\n", path, err)
+				os.Exit(1)
+			}
+			objects2 = append(objects2, pathObjects...)
+		}
+	}
+
+	writeDiff(revs[0], label2, objects1, objects2)
+}
+
+// readRevisionObjects parses the manifest files found under paths at a git
+// revision. Objects are labelled with "<rev>:<path>" as their source file,
+// where path is relative to the repository root.
+func readRevisionObjects(rev string, paths []string) ([]K8sObject, error) {
+	listing, err := runGit(append([]string{"ls-tree", "-r", "-z", "--name-only", "--full-name", rev, "--"}, paths...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []K8sObject
+	for _, file := range strings.Split(strings.TrimSuffix(string(listing), "\x00"), "\x00") {
+		if !isManifestFile(file) {
+			continue
+		}
+		content, err := runGit("cat-file", "blob", rev+":"+file)
+		if err != nil {
+			return nil, err
+		}
+		name := rev + ":" + file
+		fileObjects, err := parseManifestData(name, content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		objects = append(objects, fileObjects...)
+	}
+	return objects, nil
+}
+
+// runGit runs a git command in the working directory and returns its output.
+// Failures are reported with git's own error message.
+func runGit(args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %v", args[0], err)
+	}
+	return out, nil
+}
@@ -2,4 +2,4 @@ module k8s-diff
 
 go 1.24.2
 
-require gopkg.in/yaml.v3 v3.0.1 // indirect
+require gopkg.in/yaml.v3 v3.0.1
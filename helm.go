@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// runHelmCommand implements
+// "k8s-diff helm <chart> [helm flags...] -- [<chart>] [helm flags...]".
+//
+// Each side is rendered with "helm template <chart> <flags...>" using the helm
+// binary from $HELM_BIN or the PATH, and the rendered manifests are diffed.
+// The flags are passed to helm unchanged, so values files (-f), --set,
+// --namespace and the like all work. The second side renders the same chart
+// unless it names another one, which makes comparing two values files or two
+// chart versions a one-liner.
+func runHelmCommand(args []string) {
+	sep := indexOf(args, "--")
+	if sep < 1 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintf(os.Stderr, "Error: helm expects <chart> [helm flags...] -- [<chart>] [helm flags...]\n\n")
+		fmt.Print(helpText)
+		os.Exit(1)
+	}
+	chart1, flags1 := args[0], args[1:sep]
+	chart2, flags2 := chart1, args[sep+1:]
+	if len(flags2) > 0 && !strings.HasPrefix(flags2[0], "-") {
+		chart2, flags2 = flags2[0], flags2[1:]
+	}
+
+	objects1, err := renderHelmChart(chart1, flags1)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error rendering %s: %v\n", chart1, err)
+		os.Exit(1)
+	}
+	objects2, err := renderHelmChart(chart2, flags2)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error rendering %s: %v\n", chart2, err)
+		os.Exit(1)
+	}
+
+	writeDiff(chart1, chart2, objects1, objects2)
+}
+
+// renderHelmChart runs helm template on a local chart and parses the output.
+func renderHelmChart(chart string, flags []string) ([]K8sObject, error) {
+	helm := os.Getenv("HELM_BIN")
+	if helm == "" {
+		helm = "helm"
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(helm, append([]string{"template", chart}, flags...)...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("helm template: %s", msg)
+		}
+		return nil, fmt.Errorf("helm template: %v", err)
+	}
+
+	return parseHelmOutput(chart, out)
+}
+
+// parseHelmOutput parses rendered chart output. helm template starts every
+// document with a "# Source: <chart>/templates/<file>" comment; each object is
+// attributed to that template file, with lines counted from the comment, so
+// positions point into the rendered template rather than the combined
+// stream. Documents without the comment are attributed to the chart.
+func parseHelmOutput(chart string, content []byte) ([]K8sObject, error) {
+	var objects []K8sObject
+
+	flush := func(lines []string) error {
+		name := chart
+		for _, line := range lines {
+			if template, ok := strings.CutPrefix(line, "# Source: "); ok {
+				name = strings.TrimSpace(template)
+				break
+			}
+		}
+		docObjects, err := parseManifestData(name, []byte(strings.Join(lines, "\n")))
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		objects = append(objects, docObjects...)
+		return nil
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.TrimRight(line, " \r") == "---" {
+			if err := flush(lines); err != nil {
+				return nil, err
+			}
+			lines = nil
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := flush(lines); err != nil {
+		return nil, err
+	}
+
+	return objects, nil
+}
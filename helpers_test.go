@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestFile writes content to a file named name in a fresh temporary
+// directory and returns its path.
+func writeTestFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// parseTestObjects parses a YAML manifest stream the way the command line
+// reads files.
+func parseTestObjects(t *testing.T, manifest string) []K8sObject {
+	t.Helper()
+	objects, err := parseK8sObjects(writeTestFile(t, "manifest.yaml", manifest))
+	if err != nil {
+		t.Fatalf("parsing manifest: %v", err)
+	}
+	return objects
+}
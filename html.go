@@ -0,0 +1,321 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// htmlObject is the template view of one compared object.
+type htmlObject struct {
+	ID        string
+	Key       string
+	Kind      string
+	Namespace string
+	Name      string
+	Status    string
+	Renamed   string // See renameNote; "" when the object was not renamed
+	Source    string // "file:line" of the object, "" when unknown
+	Changes   []htmlChange
+	Before    string
+	After     string
+	Search    string // Lowercased text matched by the search box
+}
+
+// htmlChange is the template view of one field change.
+type htmlChange struct {
+	Type   string
+	Path   string
+	Old    string
+	New    string
+	Source string
+}
+
+// htmlNavKind groups navigation entries by kind and then namespace.
+type htmlNavKind struct {
+	Kind       string
+	Namespaces []htmlNavNamespace
+}
+
+type htmlNavNamespace struct {
+	Namespace string
+	Objects   []htmlObject
+}
+
+// writeHTMLReport renders the diff as a single self-contained HTML page.
+//
+// The page has a navigation tree by kind and namespace, search and filter
+// controls, and one expandable section per object containing its field-level
+// changes plus the full before/after YAML. All CSS and JavaScript are inlined
+// so the file works offline, e.g. when opened from a CI artifact.
+// Secret data and last-applied configurations are redacted in both the change
+// list and the YAML.
+func writeHTMLReport(w io.Writer, file1, file2 string, objects1, objects2 []K8sObject) {
+	var objects []htmlObject
+	counts := make(map[string]int)
+
+	for i, pair := range matchObjects(objects1, objects2) {
+		status := pairStatus(pair)
+		counts[status]++
+
+		obj := pair.New
+		if obj == nil {
+			obj = pair.Old
+		}
+		view := htmlObject{
+			ID:        fmt.Sprintf("obj-%d", i),
+			Key:       pair.Key,
+			Kind:      obj.Kind,
+			Namespace: getObjectNamespace(*obj),
+			Name:      getObjectName(*obj),
+			Status:    status,
+			Renamed:   renameNote(pair),
+		}
+		if view.Namespace == "" {
+			view.Namespace = "(none)"
+		}
+		if pos, ok := pairSource(pair); ok {
+			view.Source = pos.String()
+		}
+
+		search := []string{pair.Key, status, view.Source, pair.RenamedFrom}
+		// The panes show the complete documents, extra top-level fields included
+		if pair.Old != nil {
+			view.Before = strings.Join(normalizedYAML(redactObject(*pair.Old)), "\n")
+		}
+		if pair.New != nil {
+			view.After = strings.Join(normalizedYAML(redactObject(*pair.New)), "\n")
+		}
+		if status == "modified" {
+			for _, change := range objectChanges(*pair.Old, *pair.New) {
+				oldText, newText := formatValue(change.Old), formatValue(change.New)
+				if isSensitivePath(*obj, change.Path) {
+					oldText, newText = redactedValue, redactedValue
+				}
+				if change.Type == changeAdded {
+					oldText = ""
+				}
+				if change.Type == changeRemoved {
+					newText = ""
+				}
+				source := ""
+				if pos, ok := changeSource(pair, change); ok {
+					source = pos.String()
+				}
+				view.Changes = append(view.Changes, htmlChange{Type: string(change.Type), Path: change.Path.String(), Old: oldText, New: newText, Source: source})
+				search = append(search, change.Path.String())
+			}
+		}
+		view.Search = strings.ToLower(strings.Join(search, " "))
+
+		objects = append(objects, view)
+	}
+
+	data := struct {
+		Title   string
+		Files   [2]string
+		Counts  map[string]int
+		Kinds   []string
+		Nav     []htmlNavKind
+		Objects []htmlObject
+	}{
+		Title:   "k8s-diff report",
+		Files:   [2]string{file1, file2},
+		Counts:  counts,
+		Nav:     htmlNavigation(objects),
+		Objects: objects,
+	}
+	for _, kind := range data.Nav {
+		data.Kinds = append(data.Kinds, kind.Kind)
+	}
+
+	if err := htmlReportTemplate.Execute(w, data); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to render HTML report: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// htmlNavigation builds the kind -> namespace -> object navigation tree,
+// sorted alphabetically at each level.
+func htmlNavigation(objects []htmlObject) []htmlNavKind {
+	tree := make(map[string]map[string][]htmlObject)
+	for _, obj := range objects {
+		if tree[obj.Kind] == nil {
+			tree[obj.Kind] = make(map[string][]htmlObject)
+		}
+		tree[obj.Kind][obj.Namespace] = append(tree[obj.Kind][obj.Namespace], obj)
+	}
+
+	var nav []htmlNavKind
+	for kind, namespaces := range tree {
+		entry := htmlNavKind{Kind: kind}
+		for namespace, objs := range namespaces {
+			sort.Slice(objs, func(i, j int) bool { return objs[i].Name < objs[j].Name })
+			entry.Namespaces = append(entry.Namespaces, htmlNavNamespace{Namespace: namespace, Objects: objs})
+		}
+		sort.Slice(entry.Namespaces, func(i, j int) bool { return entry.Namespaces[i].Namespace < entry.Namespaces[j].Namespace })
+		nav = append(nav, entry)
+	}
+	sort.Slice(nav, func(i, j int) bool { return nav[i].Kind < nav[j].Kind })
+	return nav
+}
+
+// htmlReportTemplate is the self-contained page layout. Objects carry their
+// kind, namespace, status and search text as data attributes so the inline
+// script can filter them without any external libraries.
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+  body { margin: 0; font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #1f2328; background: #f6f8fa; }
+  header { padding: 12px 20px; background: #24292f; color: #fff; }
+  header h1 { margin: 0 0 4px; font-size: 20px; }
+  header .files { font-family: monospace; font-size: 12px; opacity: 0.8; }
+  .layout { display: flex; align-items: flex-start; }
+  nav { width: 280px; flex-shrink: 0; position: sticky; top: 0; max-height: 100vh; overflow-y: auto; padding: 12px; box-sizing: border-box; border-right: 1px solid #d0d7de; background: #fff; font-size: 13px; }
+  nav ul { list-style: none; margin: 0; padding-left: 12px; }
+  nav > ul { padding-left: 0; }
+  nav a { color: #0969da; text-decoration: none; }
+  nav a:hover { text-decoration: underline; }
+  nav .kind { font-weight: 600; margin-top: 8px; }
+  nav .namespace { color: #57606a; margin-top: 4px; }
+  main { flex-grow: 1; padding: 12px 20px; min-width: 0; }
+  .controls { display: flex; flex-wrap: wrap; gap: 12px; align-items: center; margin-bottom: 12px; }
+  .controls input[type=search] { padding: 6px 8px; width: 280px; border: 1px solid #d0d7de; border-radius: 6px; }
+  .controls select { padding: 5px; border: 1px solid #d0d7de; border-radius: 6px; }
+  .summary span { margin-right: 12px; }
+  details.object { background: #fff; border: 1px solid #d0d7de; border-radius: 6px; margin-bottom: 8px; }
+  details.object > summary { padding: 8px 12px; cursor: pointer; font-family: monospace; }
+  details.object[hidden] { display: none; }
+  .body { padding: 0 12px 12px; }
+  .badge { display: inline-block; min-width: 72px; text-align: center; border-radius: 10px; padding: 1px 8px; margin-right: 8px; font-size: 12px; font-family: sans-serif; color: #fff; }
+  .added { background: #1a7f37; } .removed { background: #cf222e; } .modified { background: #9a6700; } .unchanged { background: #6e7781; }
+  table { border-collapse: collapse; width: 100%; font-family: monospace; font-size: 13px; margin: 8px 0; }
+  td, th { border: 1px solid #d0d7de; padding: 4px 6px; vertical-align: top; text-align: left; word-break: break-all; }
+  tr.change-added td { background: #dafbe1; } tr.change-removed td { background: #ffebe9; }
+  tr.change-modified td { background: #fff8c5; } tr.change-type-only td { background: #f6f8fa; color: #57606a; }
+  td.old { color: #cf222e; } td.new { color: #1a7f37; }
+  .source { color: #57606a; font-size: 12px; }
+  .yaml { display: flex; gap: 12px; }
+  .yaml > div { flex: 1; min-width: 0; }
+  .yaml h4 { margin: 8px 0 4px; font-size: 13px; }
+  pre { margin: 0; padding: 8px; background: #f6f8fa; border: 1px solid #d0d7de; border-radius: 6px; overflow-x: auto; font-size: 12px; }
+  .empty { color: #57606a; font-style: italic; }
+</style>
+</head>
+<body>
+<header>
+  <h1>{{.Title}}</h1>
+  <div class="files">{{index .Files 0}} &rarr; {{index .Files 1}}</div>
+</header>
+<div class="layout">
+<nav>
+  <ul>
+  {{- range .Nav}}
+    <li><div class="kind">{{.Kind}}</div>
+      <ul>
+      {{- range .Namespaces}}
+        <li><div class="namespace">{{.Namespace}}</div>
+          <ul>
+          {{- range .Objects}}
+            <li data-nav="{{.ID}}"><a href="#{{.ID}}">{{.Name}}</a> <small>({{.Status}})</small></li>
+          {{- end}}
+          </ul>
+        </li>
+      {{- end}}
+      </ul>
+    </li>
+  {{- end}}
+  </ul>
+</nav>
+<main>
+  <div class="summary">
+    <span class="badge added">added</span>{{index .Counts "added"}}
+    <span class="badge removed">removed</span>{{index .Counts "removed"}}
+    <span class="badge modified">modified</span>{{index .Counts "modified"}}
+    <span class="badge unchanged">unchanged</span>{{index .Counts "unchanged"}}
+  </div>
+  <div class="controls">
+    <input type="search" id="search" placeholder="Search objects and field paths...">
+    <select id="kind">
+      <option value="">All kinds</option>
+      {{- range .Kinds}}
+      <option value="{{.}}">{{.}}</option>
+      {{- end}}
+    </select>
+    <label><input type="checkbox" class="status" value="added" checked> added</label>
+    <label><input type="checkbox" class="status" value="removed" checked> removed</label>
+    <label><input type="checkbox" class="status" value="modified" checked> modified</label>
+    <label><input type="checkbox" class="status" value="unchanged"> unchanged</label>
+    <button type="button" id="expand">Expand all</button>
+    <button type="button" id="collapse">Collapse all</button>
+  </div>
+  {{- range .Objects}}
+  <details class="object" id="{{.ID}}" data-kind="{{.Kind}}" data-status="{{.Status}}" data-search="{{.Search}}">
+    <summary><span class="badge {{.Status}}">{{.Status}}</span>{{.Key}}{{if .Renamed}} <span class="source">({{.Renamed}})</span>{{end}}{{if .Source}} <span class="source">{{.Source}}</span>{{end}}</summary>
+    <div class="body">
+      {{- if .Changes}}
+      <table>
+        <tr><th>Change</th><th>Path</th><th>Old</th><th>New</th><th>Source</th></tr>
+        {{- range .Changes}}
+        <tr class="change-{{.Type}}"><td>{{.Type}}</td><td>{{.Path}}</td><td class="old">{{.Old}}</td><td class="new">{{.New}}</td><td class="source">{{.Source}}</td></tr>
+        {{- end}}
+      </table>
+      {{- end}}
+      <div class="yaml">
+        <div><h4>Before</h4>{{if .Before}}<pre>{{.Before}}</pre>{{else}}<p class="empty">Object does not exist</p>{{end}}</div>
+        <div><h4>After</h4>{{if .After}}<pre>{{.After}}</pre>{{else}}<p class="empty">Object does not exist</p>{{end}}</div>
+      </div>
+    </div>
+  </details>
+  {{- end}}
+</main>
+</div>
+<script>
+(function () {
+  var search = document.getElementById("search");
+  var kind = document.getElementById("kind");
+  var statuses = document.querySelectorAll("input.status");
+  var objects = document.querySelectorAll("details.object");
+
+  function applyFilters() {
+    var text = search.value.toLowerCase();
+    var shown = {};
+    statuses.forEach(function (box) { shown[box.value] = box.checked; });
+    objects.forEach(function (obj) {
+      var visible = shown[obj.dataset.status] &&
+        (kind.value === "" || obj.dataset.kind === kind.value) &&
+        (text === "" || obj.dataset.search.indexOf(text) !== -1);
+      obj.hidden = !visible;
+      var navItem = document.querySelector('[data-nav="' + obj.id + '"]');
+      if (navItem) { navItem.style.display = visible ? "" : "none"; }
+    });
+  }
+
+  search.addEventListener("input", applyFilters);
+  kind.addEventListener("change", applyFilters);
+  statuses.forEach(function (box) { box.addEventListener("change", applyFilters); });
+  document.getElementById("expand").addEventListener("click", function () {
+    objects.forEach(function (obj) { if (!obj.hidden) { obj.open = true; } });
+  });
+  document.getElementById("collapse").addEventListener("click", function () {
+    objects.forEach(function (obj) { obj.open = false; });
+  });
+  document.querySelectorAll("nav a").forEach(function (link) {
+    link.addEventListener("click", function () {
+      var target = document.getElementById(link.getAttribute("href").slice(1));
+      if (target) { target.open = true; }
+    });
+  });
+  applyFilters();
+})();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// looksLikeJSON reports whether content starts with a JSON object or array.
+func looksLikeJSON(content []byte) bool {
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf")), " \t\r\n")
+	return len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[')
+}
+
+// parseJSONManifests parses a stream of JSON values: a single document (as
+// written by "kubectl get -o json"), several concatenated documents, or JSON
+// Lines with one object per line. Each value counts as one document; Lists and
+// arrays are expanded like in YAML input (see nodeObjects).
+//
+// Values are read into yaml.v3 nodes so numbers are decoded exactly like in
+// YAML manifests and every field keeps its line and column. Should yaml.v3
+// reject a value, it is decoded with encoding/json instead and its fields are
+// all attributed to the line the value starts on.
+func parseJSONManifests(name string, content []byte) ([]K8sObject, error) {
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	var objects []K8sObject
+
+	for i := 1; ; i++ {
+		offset := int(decoder.InputOffset())
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to parse object %d: %v", i, err)
+		}
+		if string(raw) == "null" {
+			continue // Skip empty documents
+		}
+
+		// The decoder's offset is before any whitespace preceding the value
+		start := offset + len(content[offset:]) - len(bytes.TrimLeft(content[offset:], " \t\r\n"))
+		line := bytes.Count(content[:start], []byte("\n")) + 1
+
+		root, err := jsonNode(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse object %d: %v", i, err)
+		}
+		docObjects, err := nodeObjects(name, i, root)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range docObjects {
+			obj.Source.shift(line - 1)
+		}
+		objects = append(objects, docObjects...)
+	}
+
+	return objects, nil
+}
+
+// jsonNode converts one JSON value into a yaml.v3 node tree whose lines are
+// relative to the start of the value.
+func jsonNode(raw []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(yamlCompatibleJSON(raw), &doc); err == nil && len(doc.Content) > 0 {
+		return doc.Content[0], nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var val interface{}
+	if err := decoder.Decode(&val); err != nil {
+		return nil, err
+	}
+	var root yaml.Node
+	if err := root.Encode(normalizeJSONValue(val)); err != nil {
+		return nil, err
+	}
+	var place func(node *yaml.Node)
+	place = func(node *yaml.Node) {
+		node.Line, node.Column = 1, 1
+		for _, child := range node.Content {
+			place(child)
+		}
+	}
+	place(&root)
+	return &root, nil
+}
+
+// yamlCompatibleJSON rewrites the two JSON constructs YAML flow syntax does
+// not accept: the "\/" escape inside strings becomes "/", and tabs between
+// tokens become spaces. Both keep every line in place, and nothing else in
+// JSON needs to change to be read as YAML.
+func yamlCompatibleJSON(raw []byte) []byte {
+	out := make([]byte, 0, len(raw))
+	inString := false
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		switch {
+		case inString && c == '\\' && i+1 < len(raw):
+			if raw[i+1] == '/' {
+				out = append(out, '/')
+			} else {
+				out = append(out, c, raw[i+1])
+			}
+			i++
+			continue
+		case c == '"':
+			inString = !inString
+		case !inString && c == '\t':
+			c = ' '
+		}
+		out = append(out, c)
+	}
+	return out
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// jsonPatchOp is a single RFC 6902 operation.
+type jsonPatchOp struct {
+	Op     string      // "add", "remove", "replace" or "move"
+	Path   string      // JSON Pointer (RFC 6901) to the target location
+	From   string      // Source pointer for "move"
+	Value  interface{} // New value for "add" and "replace"
+	Source string      // "file:line" the operation is reported at, "" when unknown
+}
+
+// MarshalJSON emits only the members each operation type defines, so that
+// null, false and zero values are still written for add/replace, plus the
+// "source" extension member when the position is known. RFC 6902 requires
+// appliers to ignore members they do not know.
+func (op jsonPatchOp) MarshalJSON() ([]byte, error) {
+	switch op.Op {
+	case "add", "replace":
+		return json.Marshal(struct {
+			Op     string      `json:"op"`
+			Path   string      `json:"path"`
+			Value  interface{} `json:"value"`
+			Source string      `json:"source,omitempty"`
+		}{op.Op, op.Path, op.Value, op.Source})
+	case "move":
+		return json.Marshal(struct {
+			Op     string `json:"op"`
+			From   string `json:"from"`
+			Path   string `json:"path"`
+			Source string `json:"source,omitempty"`
+		}{op.Op, op.From, op.Path, op.Source})
+	}
+	return json.Marshal(struct {
+		Op     string `json:"op"`
+		Path   string `json:"path"`
+		Source string `json:"source,omitempty"`
+	}{op.Op, op.Path, op.Source})
+}
+
+// writeJSONPatches prints one RFC 6902 JSON Patch per modified object as a
+// JSON document keyed by object identity ("Kind/Name" or "Kind/Namespace/Name"):
+//
+//	{
+//	  "Pod/example-pod": [
+//	    {"op": "replace", "path": "/spec/containers/0/image", "value": "nginx:1.22", "source": "new.yaml:9"}
+//	  ]
+//	}
+//
+// Operations are ordered so that every array index is valid at the moment the
+// operation is applied, i.e. after all earlier operations in the same patch.
+// Objects that were added or removed have no old/new document to patch and
+// are left out, as are unchanged objects.
+func writeJSONPatches(w io.Writer, objects1, objects2 []K8sObject) error {
+	patches := make(map[string][]jsonPatchOp)
+	for _, pair := range matchObjects(objects1, objects2) {
+		if pairStatus(pair) != "modified" {
+			continue
+		}
+		ops := objectJSONPatch(*pair.Old, *pair.New)
+		for i := range ops {
+			if pos, ok := jsonPatchSource(*pair.New, ops[i]); ok {
+				ops[i].Source = pos.String()
+			}
+		}
+		patches[pair.Key] = ops
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(patches)
+}
+
+// jsonPatchSource returns the position of an operation in the new version of
+// the object: its target for "add", "replace" and "move", and the value a
+// "remove" takes its target out of, since the removed value is gone there.
+// Operation paths address the new document's layout once earlier operations
+// are applied, so they can be looked up there directly.
+func jsonPatchSource(obj K8sObject, op jsonPatchOp) (sourcePosition, bool) {
+	pointer := op.Path
+	if op.Op == "remove" {
+		pointer = pointer[:strings.LastIndex(pointer, "/")]
+	}
+	return obj.Source.pointerPosition(pointer)
+}
+
+// objectJSONPatch builds the JSON Patch turning obj1 into obj2, walking the
+// top-level fields in objectFields order.
+func objectJSONPatch(obj1, obj2 K8sObject) []jsonPatchOp {
+	var ops []jsonPatchOp
+	for _, field := range unionFields(obj1, obj2) {
+		val1, ok1 := objectField(obj1, field)
+		val2, ok2 := objectField(obj2, field)
+		path := "/" + escapeJSONPointer(field)
+		switch {
+		case ok1 && ok2:
+			ops = append(ops, jsonPatchValue(path, val1, val2)...)
+		case ok1:
+			ops = append(ops, jsonPatchOp{Op: "remove", Path: path})
+		case ok2:
+			ops = append(ops, jsonPatchOp{Op: "add", Path: path, Value: val2})
+		}
+	}
+	return ops
+}
+
+// jsonPatchValue recursively compares two values at a JSON Pointer location.
+//
+//   - Maps: removed keys are removed, new keys added, shared keys recursed into
+//   - Container arrays: containers are matched by name, using "move" to restore
+//     the new order (see jsonPatchContainers)
+//   - Other arrays: elements are aligned with a longest-common-subsequence diff
+//     (see jsonPatchArray)
+//   - Scalars and type changes: a single "replace"
+//
+// Type-only changes are replaced too; a patch must reproduce the new value exactly.
+func jsonPatchValue(path string, val1, val2 interface{}) []jsonPatchOp {
+	if reflect.DeepEqual(val1, val2) {
+		return nil
+	}
+
+	switch v1 := val1.(type) {
+	case map[string]interface{}:
+		if v2, ok := val2.(map[string]interface{}); ok {
+			var ops []jsonPatchOp
+			for _, key := range unionKeys(v1, v2) {
+				c1, has1 := v1[key]
+				c2, has2 := v2[key]
+				childPath := path + "/" + escapeJSONPointer(key)
+				switch {
+				case has1 && has2:
+					ops = append(ops, jsonPatchValue(childPath, c1, c2)...)
+				case has1:
+					ops = append(ops, jsonPatchOp{Op: "remove", Path: childPath})
+				default:
+					ops = append(ops, jsonPatchOp{Op: "add", Path: childPath, Value: c2})
+				}
+			}
+			return ops
+		}
+	case []interface{}:
+		if v2, ok := val2.([]interface{}); ok {
+			if isContainerArray(v1) && isContainerArray(v2) {
+				return jsonPatchContainers(path, v1, v2)
+			}
+			return jsonPatchArray(path, v1, v2)
+		}
+	}
+
+	return []jsonPatchOp{{Op: "replace", Path: path, Value: val2}}
+}
+
+// jsonPatchContainers transforms one container array into another while
+// tracking the current array layout, so every emitted index is exact:
+//
+//  1. Containers missing from the new array are removed, highest index first
+//  2. For each target position, the expected container is moved into place
+//     (or added if new), then its fields are patched at that position
+//
+// Containers without a name fall back to the generic array algorithm.
+func jsonPatchContainers(path string, slice1, slice2 []interface{}) []jsonPatchOp {
+	containers1, names1 := containersByName(slice1)
+	containers2, names2 := containersByName(slice2)
+	if len(names1) != len(slice1) || len(names2) != len(slice2) {
+		return jsonPatchArray(path, slice1, slice2)
+	}
+
+	var ops []jsonPatchOp
+	current := append([]string(nil), names1...)
+
+	for i := len(current) - 1; i >= 0; i-- {
+		if _, kept := containers2[current[i]]; !kept {
+			ops = append(ops, jsonPatchOp{Op: "remove", Path: fmt.Sprintf("%s/%d", path, i)})
+			current = append(current[:i], current[i+1:]...)
+		}
+	}
+
+	for j, name := range names2 {
+		itemPath := fmt.Sprintf("%s/%d", path, j)
+		k := indexOf(current, name)
+		switch {
+		case k == -1:
+			ops = append(ops, jsonPatchOp{Op: "add", Path: itemPath, Value: containers2[name]})
+			current = append(current[:j], append([]string{name}, current[j:]...)...)
+			continue
+		case k != j:
+			ops = append(ops, jsonPatchOp{Op: "move", From: fmt.Sprintf("%s/%d", path, k), Path: itemPath})
+			current = append(current[:k], current[k+1:]...)
+			current = append(current[:j], append([]string{name}, current[j:]...)...)
+		}
+		ops = append(ops, jsonPatchValue(itemPath, containers1[name], containers2[name])...)
+	}
+
+	return ops
+}
+
+// jsonPatchArray aligns two arrays with the line diff algorithm (diffLines),
+// using each element's canonical JSON as its identity. Unchanged elements keep
+// their place; a run of removals followed by a run of additions is patched
+// element by element where the runs overlap, so a modified element yields
+// nested operations rather than a remove/add pair.
+func jsonPatchArray(path string, slice1, slice2 []interface{}) []jsonPatchOp {
+	edits := diffLines(canonicalElements(slice1), canonicalElements(slice2))
+
+	var ops []jsonPatchOp
+	index, oldIndex, newIndex := 0, 0, 0 // Position in the patched array and in each input
+	for i := 0; i < len(edits); {
+		if edits[i].Op == ' ' {
+			index, oldIndex, newIndex = index+1, oldIndex+1, newIndex+1
+			i++
+			continue
+		}
+
+		// Collect a run of removals and the additions that follow it
+		removed, added := 0, 0
+		for i < len(edits) && edits[i].Op == '-' {
+			removed++
+			i++
+		}
+		for i < len(edits) && edits[i].Op == '+' {
+			added++
+			i++
+		}
+
+		paired := min(removed, added)
+		for n := 0; n < paired; n++ {
+			ops = append(ops, jsonPatchValue(fmt.Sprintf("%s/%d", path, index), slice1[oldIndex], slice2[newIndex])...)
+			index, oldIndex, newIndex = index+1, oldIndex+1, newIndex+1
+		}
+		for n := paired; n < removed; n++ {
+			ops = append(ops, jsonPatchOp{Op: "remove", Path: fmt.Sprintf("%s/%d", path, index)})
+			oldIndex++
+		}
+		for n := paired; n < added; n++ {
+			ops = append(ops, jsonPatchOp{Op: "add", Path: fmt.Sprintf("%s/%d", path, index), Value: slice2[newIndex]})
+			index, newIndex = index+1, newIndex+1
+		}
+	}
+
+	return ops
+}
+
+// canonicalElements renders array elements as canonical JSON strings so they
+// can be compared with the line diff algorithm. JSON object keys are sorted.
+func canonicalElements(slice []interface{}) []string {
+	keys := make([]string, len(slice))
+	for i, item := range slice {
+		encoded, err := json.Marshal(item)
+		if err != nil {
+			encoded = []byte(fmt.Sprintf("%#v", item))
+		}
+		keys[i] = string(encoded)
+	}
+	return keys
+}
+
+// escapeJSONPointer escapes a map key for use as a JSON Pointer token (RFC 6901).
+func escapeJSONPointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+// indexOf returns the position of item in slice, or -1.
+func indexOf(slice []string, item string) int {
+	for i, s := range slice {
+		if s == item {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// applyTestJSONPatch is a minimal RFC 6902 applier used to check that the
+// generated operations are valid in sequence. It fails the test on any index
+// or path that does not exist at the time the operation is applied.
+func applyTestJSONPatch(t *testing.T, doc interface{}, ops []jsonPatchOp) interface{} {
+	t.Helper()
+	for n, op := range ops {
+		var err error
+		switch op.Op {
+		case "add", "replace", "remove":
+			doc, err = patchPointer(doc, splitPointer(op.Path), op.Op, deepCopyValue(op.Value))
+		case "move":
+			var moved interface{}
+			moved, err = getPointer(doc, splitPointer(op.From))
+			if err == nil {
+				doc, err = patchPointer(doc, splitPointer(op.From), "remove", nil)
+			}
+			if err == nil {
+				doc, err = patchPointer(doc, splitPointer(op.Path), "add", moved)
+			}
+		default:
+			err = fmt.Errorf("unknown op")
+		}
+		if err != nil {
+			t.Fatalf("applying op %d %+v: %v", n, op, err)
+		}
+	}
+	return doc
+}
+
+func splitPointer(pointer string) []string {
+	if pointer == "" {
+		return nil
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens
+}
+
+func getPointer(doc interface{}, tokens []string) (interface{}, error) {
+	for _, token := range tokens {
+		switch d := doc.(type) {
+		case map[string]interface{}:
+			val, ok := d[token]
+			if !ok {
+				return nil, fmt.Errorf("no key %q", token)
+			}
+			doc = val
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(d) {
+				return nil, fmt.Errorf("index %q out of range for length %d", token, len(d))
+			}
+			doc = d[i]
+		default:
+			return nil, fmt.Errorf("cannot index %T with %q", doc, token)
+		}
+	}
+	return doc, nil
+}
+
+// patchPointer applies add, replace or remove at the location of tokens and
+// returns the updated document.
+func patchPointer(doc interface{}, tokens []string, op string, val interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return val, nil
+	}
+	parent, err := getPointer(doc, tokens[:len(tokens)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := tokens[len(tokens)-1]
+
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := p[last]; !ok && op != "add" {
+			return nil, fmt.Errorf("no key %q to %s", last, op)
+		}
+		if op == "remove" {
+			delete(p, last)
+		} else {
+			p[last] = val
+		}
+		return doc, nil
+	case []interface{}:
+		i, err := strconv.Atoi(last)
+		limit := len(p)
+		if op == "add" {
+			limit++
+		}
+		if err != nil || i < 0 || i >= limit {
+			return nil, fmt.Errorf("index %q out of range for %s on length %d", last, op, len(p))
+		}
+		var updated []interface{}
+		switch op {
+		case "add":
+			updated = append(append(append([]interface{}(nil), p[:i]...), val), p[i:]...)
+		case "replace":
+			p[i] = val
+			return doc, nil
+		case "remove":
+			updated = append(append([]interface{}(nil), p[:i]...), p[i+1:]...)
+		}
+		return patchPointer(doc, tokens[:len(tokens)-1], "replace", updated)
+	}
+	return nil, fmt.Errorf("cannot patch %T", parent)
+}
+
+func TestObjectJSONPatchRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+	}{
+		{
+			name: "containers reordered, removed and added",
+			old: `apiVersion: v1
+kind: Pod
+metadata: {name: p}
+spec:
+  containers:
+  - {name: a, image: a:1}
+  - {name: b, image: b:1}
+  - {name: c, image: c:1}
+  - {name: d, image: d:1}
+`,
+			new: `apiVersion: v1
+kind: Pod
+metadata: {name: p}
+spec:
+  containers:
+  - {name: d, image: d:2}
+  - {name: e, image: e:1}
+  - {name: b, image: b:1}
+  - {name: a, image: a:1, args: [x]}
+`,
+		},
+		{
+			name: "plain array insertions and removals",
+			old: `apiVersion: v1
+kind: ConfigMap
+metadata: {name: c}
+data:
+  list: [1, 2, 3, 4, 5]
+`,
+			new: `apiVersion: v1
+kind: ConfigMap
+metadata: {name: c, labels: {app: x}}
+data:
+  list: [0, 2, 3, 9, 5, 6, 7]
+`,
+		},
+		{
+			name: "removal after insertions shifted the indices",
+			old: `apiVersion: v1
+kind: ConfigMap
+metadata: {name: c}
+data:
+  list: [1, 2, 3, 4]
+`,
+			new: `apiVersion: v1
+kind: ConfigMap
+metadata: {name: c}
+data:
+  list: [0, 0, 1, 2, 4]
+`,
+		},
+		{
+			name: "modified array elements and removed keys",
+			old: `apiVersion: v1
+kind: Service
+metadata: {name: s, annotations: {a/b: "1", c~d: "2"}}
+spec:
+  ports:
+  - {port: 80, protocol: TCP}
+  - {port: 443, protocol: TCP}
+  - {port: 8080}
+`,
+			new: `apiVersion: v1
+kind: Service
+metadata: {name: s, annotations: {a/b: "3"}}
+spec:
+  ports:
+  - {port: 81, protocol: TCP}
+  - {port: 443, protocol: UDP}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj1 := parseTestObjects(t, tt.old)[0]
+			obj2 := parseTestObjects(t, tt.new)[0]
+
+			ops := objectJSONPatch(obj1, obj2)
+			got := applyTestJSONPatch(t, deepCopyValue(objectToMap(obj1)), ops)
+			if want := objectToMap(obj2); !reflect.DeepEqual(got, want) {
+				t.Errorf("patched object =\n%v\nwant\n%v\nops: %+v", got, want, ops)
+			}
+		})
+	}
+}
+
+func TestJSONPatchContainersOrder(t *testing.T) {
+	container := func(name string) map[string]interface{} {
+		return map[string]interface{}{"name": name, "image": name + ":1"}
+	}
+	old := []interface{}{container("a"), container("b"), container("c")}
+	new := []interface{}{container("c"), container("a"), container("d")}
+
+	// Removals come first, highest index first; then each position is filled
+	// by a move or an add, using the indices of the array as patched so far.
+	want := []jsonPatchOp{
+		{Op: "remove", Path: "/c/1"},
+		{Op: "move", From: "/c/1", Path: "/c/0"},
+		{Op: "add", Path: "/c/2", Value: container("d")},
+	}
+	if got := jsonPatchContainers("/c", old, new); !reflect.DeepEqual(got, want) {
+		t.Errorf("jsonPatchContainers =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestWriteJSONPatchesSource(t *testing.T) {
+	objects1 := parseTestObjects(t, "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: c\ndata:\n  a: \"1\"\n  b: \"2\"\n")
+	objects2 := parseTestObjects(t, "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: c\ndata:\n  a: \"3\"\n")
+
+	var buf bytes.Buffer
+	if err := writeJSONPatches(&buf, objects1, objects2); err != nil {
+		t.Fatal(err)
+	}
+	var patches map[string][]map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &patches); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+
+	ops := patches["ConfigMap/c"]
+	if len(ops) != 2 {
+		t.Fatalf("got %d ops, want 2: %s", len(ops), buf.String())
+	}
+	// The replace points at its value, the remove at the map it left.
+	for i, want := range []string{"manifest.yaml:6", "manifest.yaml:5"} {
+		if source, _ := ops[i]["source"].(string); !strings.HasSuffix(source, want) {
+			t.Errorf("op %d source = %q, want suffix %q", i, source, want)
+		}
+	}
+}
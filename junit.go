@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// junitTestSuites is the root element of a JUnit XML report.
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite groups the test cases of one kind.
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase is one compared object.
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr,omitempty"`
+	Line      int           `xml:"line,attr,omitempty"`
+	Failure   *junitFailure `xml:"failure"`
+}
+
+// junitFailure explains why an object failed the drift check.
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// writeJUnitReport prints a JUnit XML report treating the first input as the
+// expected state and the second as the actual state. Every compared object is
+// a test case, grouped into one test suite per kind: unchanged objects pass,
+// and objects that drifted, are missing from the actual state or are
+// unexpected in it fail. Failure bodies list the field-level diff one leaf per
+// line (see objectLeafChanges), with Secret data and last-applied
+// configurations redacted. Test case names are the object identity, so
+// dashboards keep a per-object pass/fail history between runs.
+func writeJUnitReport(w io.Writer, objects1, objects2 []K8sObject) error {
+	report := junitTestSuites{Name: "k8s-diff"}
+	suiteIndex := make(map[string]int)
+
+	for _, pair := range matchObjects(objects1, objects2) {
+		obj := pair.New
+		if obj == nil {
+			obj = pair.Old
+		}
+
+		testCase := junitTestCase{Name: pair.Key, ClassName: "k8s-diff." + obj.Kind}
+		if pos, ok := pairSource(pair); ok {
+			testCase.File, testCase.Line = pos.File, pos.Line
+		}
+
+		switch pairStatus(pair) {
+		case "modified":
+			changes := objectLeafChanges(*pair.Old, *pair.New)
+			var body strings.Builder
+			for _, change := range changes {
+				body.WriteString(formatChange(change, isSensitivePath(*obj, change.Path)))
+				if pos, ok := changeSource(pair, change); ok {
+					fmt.Fprintf(&body, "  (%s)", pos)
+				}
+				body.WriteString("\n")
+			}
+			testCase.Failure = &junitFailure{
+				Message: fmt.Sprintf("%s drifted (%d field change(s))", pair.Key, len(changes)),
+				Type:    "drift",
+				Body:    body.String(),
+			}
+		case "removed":
+			testCase.Failure = &junitFailure{Message: pair.Key + " is missing", Type: "missing"}
+		case "added":
+			testCase.Failure = &junitFailure{Message: pair.Key + " is unexpected", Type: "unexpected"}
+		}
+
+		i, ok := suiteIndex[obj.Kind]
+		if !ok {
+			i = len(report.Suites)
+			suiteIndex[obj.Kind] = i
+			report.Suites = append(report.Suites, junitTestSuite{Name: obj.Kind})
+		}
+		suite := &report.Suites[i]
+		suite.Cases = append(suite.Cases, testCase)
+		suite.Tests++
+		report.Tests++
+		if testCase.Failure != nil {
+			suite.Failures++
+			report.Failures++
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// kubeconfig is the subset of the kubeconfig file format needed to reach an
+// API server: server address, TLS settings, and token, basic or client
+// certificate authentication. Exec and auth-provider plugins are not supported.
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster   string `yaml:"cluster"`
+			User      string `yaml:"user"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token                 string `yaml:"token"`
+			TokenFile             string `yaml:"tokenFile"`
+			Username              string `yaml:"username"`
+			Password              string `yaml:"password"`
+			ClientCertificate     string `yaml:"client-certificate"`
+			ClientCertificateData string `yaml:"client-certificate-data"`
+			ClientKey             string `yaml:"client-key"`
+			ClientKeyData         string `yaml:"client-key-data"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+}
+
+// apiResource describes how a kind is served by the API server.
+type apiResource struct {
+	Name       string `json:"name"`
+	Kind       string `json:"kind"`
+	Namespaced bool   `json:"namespaced"`
+}
+
+// apiFetcher fetches live objects from a Kubernetes API server over plain
+// HTTP(S), using API discovery to map kinds to resource paths. Objects without
+// a namespace are looked up in the namespace of the kubeconfig context, or
+// "default".
+type apiFetcher struct {
+	server    string
+	namespace string
+	token     string
+	username  string
+	password  string
+	client    *http.Client
+	resources map[string][]apiResource // Discovery results by apiVersion
+}
+
+// newAPIFetcher connects to the cluster of a kubeconfig context. An empty path
+// means the first file in $KUBECONFIG, or ~/.kube/config; an empty context
+// means the kubeconfig's current context. Relative file paths inside the
+// kubeconfig are resolved against its directory, like kubectl does.
+func newAPIFetcher(path, contextName string) (*apiFetcher, error) {
+	if path == "" {
+		path = defaultKubeconfigPath()
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading kubeconfig: %v", err)
+	}
+	var config kubeconfig
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig %s: %v", path, err)
+	}
+	resolve := func(file string) string {
+		if file == "" || filepath.IsAbs(file) {
+			return file
+		}
+		return filepath.Join(filepath.Dir(path), file)
+	}
+
+	if contextName == "" {
+		contextName = config.CurrentContext
+	}
+	contextIndex := -1
+	for i, ctx := range config.Contexts {
+		if ctx.Name == contextName {
+			contextIndex = i
+		}
+	}
+	if contextIndex < 0 {
+		return nil, fmt.Errorf("context '%s' not found in kubeconfig %s", contextName, path)
+	}
+	context := config.Contexts[contextIndex].Context
+
+	fetcher := &apiFetcher{
+		namespace: context.Namespace,
+		resources: make(map[string][]apiResource),
+	}
+	if fetcher.namespace == "" {
+		fetcher.namespace = "default"
+	}
+
+	tlsConfig := &tls.Config{}
+	foundCluster := false
+	for _, cluster := range config.Clusters {
+		if cluster.Name != context.Cluster {
+			continue
+		}
+		foundCluster = true
+		fetcher.server = strings.TrimSuffix(cluster.Cluster.Server, "/")
+		tlsConfig.InsecureSkipVerify = cluster.Cluster.InsecureSkipTLSVerify
+
+		ca, err := kubeconfigBytes(cluster.Cluster.CertificateAuthorityData, resolve(cluster.Cluster.CertificateAuthority))
+		if err != nil {
+			return nil, fmt.Errorf("reading certificate authority: %v", err)
+		}
+		if ca != nil {
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(ca) {
+				return nil, fmt.Errorf("no certificates found in the certificate authority of cluster '%s'", cluster.Name)
+			}
+			tlsConfig.RootCAs = pool
+		}
+	}
+	if !foundCluster {
+		return nil, fmt.Errorf("cluster '%s' not found in kubeconfig %s", context.Cluster, path)
+	}
+
+	for _, user := range config.Users {
+		if user.Name != context.User {
+			continue
+		}
+		fetcher.token = user.User.Token
+		fetcher.username, fetcher.password = user.User.Username, user.User.Password
+		if user.User.TokenFile != "" {
+			token, err := os.ReadFile(resolve(user.User.TokenFile))
+			if err != nil {
+				return nil, fmt.Errorf("reading token file: %v", err)
+			}
+			fetcher.token = strings.TrimSpace(string(token))
+		}
+
+		cert, err := kubeconfigBytes(user.User.ClientCertificateData, resolve(user.User.ClientCertificate))
+		if err != nil {
+			return nil, fmt.Errorf("reading client certificate: %v", err)
+		}
+		key, err := kubeconfigBytes(user.User.ClientKeyData, resolve(user.User.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("reading client key: %v", err)
+		}
+		if cert != nil && key != nil {
+			pair, err := tls.X509KeyPair(cert, key)
+			if err != nil {
+				return nil, fmt.Errorf("invalid client certificate: %v", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{pair}
+		}
+	}
+
+	fetcher.client = &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment},
+	}
+	return fetcher, nil
+}
+
+// defaultKubeconfigPath returns the kubeconfig kubectl would use. Only the
+// first file of a $KUBECONFIG list is read; lists are not merged.
+func defaultKubeconfigPath() string {
+	if env := os.Getenv("KUBECONFIG"); env != "" {
+		return filepath.SplitList(env)[0]
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".kube", "config")
+}
+
+// kubeconfigBytes returns inline base64 data, or the content of file, or nil
+// when neither is set.
+func kubeconfigBytes(data, file string) ([]byte, error) {
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	if file != "" {
+		return os.ReadFile(file)
+	}
+	return nil, nil
+}
+
+// Fetch gets the live object with obj's identity from the API server.
+func (f *apiFetcher) Fetch(obj K8sObject) (map[string]interface{}, error) {
+	resource, err := f.resource(obj.APIVersion, obj.Kind)
+	if err != nil {
+		return nil, err
+	}
+
+	base := "/api/" + obj.APIVersion
+	if strings.Contains(obj.APIVersion, "/") {
+		base = "/apis/" + obj.APIVersion
+	}
+	path := base + "/" + resource.Name + "/" + url.PathEscape(getObjectName(obj))
+	if resource.Namespaced {
+		namespace := getObjectNamespace(obj)
+		if namespace == "" {
+			namespace = f.namespace
+		}
+		path = base + "/namespaces/" + url.PathEscape(namespace) + "/" + resource.Name + "/" + url.PathEscape(getObjectName(obj))
+	}
+
+	var live interface{}
+	found, err := f.get(path, &live)
+	if err != nil || !found {
+		return nil, err
+	}
+	object, ok := normalizeJSONValue(live).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("GET %s: expected an object, got %s", path, formatValue(live))
+	}
+	return object, nil
+}
+
+// resource finds the API resource serving a kind, using (and caching) the
+// discovery document of its group version.
+func (f *apiFetcher) resource(apiVersion, kind string) (apiResource, error) {
+	resources, ok := f.resources[apiVersion]
+	if !ok {
+		path := "/api/" + apiVersion
+		if strings.Contains(apiVersion, "/") {
+			path = "/apis/" + apiVersion
+		}
+		var list struct {
+			Resources []apiResource `json:"resources"`
+		}
+		found, err := f.get(path, &list)
+		if err != nil {
+			return apiResource{}, err
+		}
+		if !found {
+			return apiResource{}, fmt.Errorf("API version %s is not served by the cluster", apiVersion)
+		}
+		resources = list.Resources
+		f.resources[apiVersion] = resources
+	}
+
+	for _, resource := range resources {
+		if resource.Kind == kind && !strings.Contains(resource.Name, "/") { // Skip subresources such as deployments/scale
+			return resource, nil
+		}
+	}
+	return apiResource{}, fmt.Errorf("kind %s is not served by the cluster under %s", kind, apiVersion)
+}
+
+// get performs an authenticated GET and decodes the JSON response into out.
+// Returns false without error for 404 Not Found.
+func (f *apiFetcher) get(path string, out interface{}) (bool, error) {
+	req, err := http.NewRequest(http.MethodGet, f.server+path, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "application/json")
+	switch {
+	case f.token != "":
+		req.Header.Set("Authorization", "Bearer "+f.token)
+	case f.username != "":
+		req.SetBasicAuth(f.username, f.password)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return false, fmt.Errorf("GET %s: %s: %s", path, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(out); err != nil {
+		return false, fmt.Errorf("GET %s: invalid response: %v", path, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// kustomizationFiles lists the file names that make a directory a kustomization.
+var kustomizationFiles = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+// runKustomizeCommand implements "k8s-diff kustomize <side1> <side2>".
+//
+// A side that is a kustomization directory is built with "kustomize build"
+// (or "kubectl kustomize" when kustomize is not installed); any other side is
+// read like a normal manifest file or directory. Hash suffixes of generated
+// ConfigMap and Secret names, and every reference to those names, are then
+// stripped on both sides, so generated objects match up across environments
+// and only real content differences are reported. For a kustomization only
+// the names of its generators are stripped (see kustomizeGeneratorNames).
+func runKustomizeCommand(args []string) {
+	if len(args) != 2 {
+		fmt.Fprintf(os.Stderr, "Error: kustomize expects exactly 2 arguments (<side1> <side2>), got %d\n\n", len(args))
+		fmt.Print(helpText)
+		os.Exit(1)
+	}
+
+	var sides [2][]K8sObject
+	for i, path := range args {
+		if err := checkFileExists(path); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		objects, err := readKustomizeSide(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error building %s: %v\n", path, err)
+			os.Exit(1)
+		}
+		sides[i] = objects
+	}
+
+	writeDiff(args[0], args[1], sides[0], sides[1])
+}
+
+// readKustomizeSide builds a kustomization directory, or parses any other
+// path as plain manifests, and strips the hash suffixes of generated names
+// (see normalizeGeneratedNames). Built objects are attributed to the
+// kustomization directory, with lines counted in the build output.
+func readKustomizeSide(path string) ([]K8sObject, error) {
+	if !isKustomization(path) {
+		objects, err := parseK8sObjects(path)
+		if err != nil {
+			return nil, err
+		}
+		normalizeGeneratedNames(objects, nil)
+		return objects, nil
+	}
+
+	generators, err := kustomizeGeneratorNames(path)
+	if err != nil {
+		return nil, err
+	}
+
+	name, args := "kustomize", []string{"build", path}
+	if _, err := exec.LookPath(name); err != nil {
+		name, args = "kubectl", []string{"kustomize", path}
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s %s: %s", name, args[0], msg)
+		}
+		return nil, fmt.Errorf("%s %s: %v", name, args[0], err)
+	}
+
+	objects, err := parseManifestData(path, out)
+	if err != nil {
+		return nil, err
+	}
+	normalizeGeneratedNames(objects, generators)
+	return objects, nil
+}
+
+// isKustomization reports whether path is a directory holding a kustomization file.
+func isKustomization(path string) bool {
+	return kustomizationFile(path) != ""
+}
+
+// kustomizationFile returns the kustomization file of a directory, or "".
+func kustomizationFile(dir string) string {
+	for _, file := range kustomizationFiles {
+		path := filepath.Join(dir, file)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// kustomization holds the fields of a kustomization file that decide the
+// names of generated ConfigMaps and Secrets.
+type kustomization struct {
+	NamePrefix         string               `yaml:"namePrefix"`
+	NameSuffix         string               `yaml:"nameSuffix"`
+	Resources          []string             `yaml:"resources"`
+	Bases              []string             `yaml:"bases"`
+	Components         []string             `yaml:"components"`
+	ConfigMapGenerator []kustomizeGenerator `yaml:"configMapGenerator"`
+	SecretGenerator    []kustomizeGenerator `yaml:"secretGenerator"`
+}
+
+// kustomizeGenerator is a configMapGenerator or secretGenerator entry.
+type kustomizeGenerator struct {
+	Name string `yaml:"name"`
+}
+
+// kustomizeGeneratorNames returns the names a kustomization's generators give
+// their objects before the hash is appended, following local resources, bases
+// and components into other kustomizations. Each level adds its namePrefix
+// and nameSuffix around the names of the levels below, as kustomize does.
+// Remote resources are skipped.
+func kustomizeGeneratorNames(dir string) (map[string]bool, error) {
+	names := make(map[string]bool)
+	return names, collectGeneratorNames(dir, make(map[string]bool), names, func(name string) string { return name })
+}
+
+// collectGeneratorNames adds the generator names of the kustomization in dir,
+// and of those it includes, to names, passing each through rename, which
+// applies the prefixes and suffixes of the including kustomizations. visited
+// holds the directories being collected, so an include cycle ends the walk.
+func collectGeneratorNames(dir string, visited, names map[string]bool, rename func(string) string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if visited[abs] {
+		return nil
+	}
+	visited[abs] = true
+	defer delete(visited, abs)
+
+	file := kustomizationFile(dir)
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	var k kustomization
+	if err := yaml.Unmarshal(content, &k); err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+
+	local := func(name string) string { return rename(k.NamePrefix + name + k.NameSuffix) }
+	for _, generator := range append(k.ConfigMapGenerator, k.SecretGenerator...) {
+		if generator.Name != "" {
+			names[local(generator.Name)] = true
+		}
+	}
+	for _, resource := range append(append(k.Resources, k.Bases...), k.Components...) {
+		sub := filepath.Join(dir, resource)
+		if !isKustomization(sub) {
+			continue // A manifest file, or a remote resource
+		}
+		if err := collectGeneratorNames(sub, visited, names, local); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalizeGeneratedNames strips the content hash suffix kustomize appends to
+// generated ConfigMap and Secret names ("app-config-7h2k9f8m5t" becomes
+// "app-config") and rewrites every string value in the same manifest set that
+// refers to a hashed name, such as volume and envFrom references. When
+// generators is not nil, only names whose base is one of its keys are
+// stripped.
+func normalizeGeneratedNames(objects []K8sObject, generators map[string]bool) {
+	renames := make(map[string]string)
+	for _, obj := range objects {
+		if obj.Kind != "ConfigMap" && obj.Kind != "Secret" {
+			continue
+		}
+		name := getObjectName(obj)
+		if base, ok := stripHashSuffix(name); ok && (generators == nil || generators[base]) {
+			renames[name] = base
+		}
+	}
+	if len(renames) == 0 {
+		return
+	}
+
+	var rewrite func(val interface{}) interface{}
+	rewrite = func(val interface{}) interface{} {
+		switch v := val.(type) {
+		case string:
+			if base, ok := renames[v]; ok {
+				return base
+			}
+		case map[string]interface{}:
+			for key, item := range v {
+				v[key] = rewrite(item)
+			}
+		case []interface{}:
+			for i, item := range v {
+				v[i] = rewrite(item)
+			}
+		}
+		return val
+	}
+
+	for i := range objects {
+		obj := &objects[i]
+		if obj.Kind == "ConfigMap" || obj.Kind == "Secret" {
+			if base, ok := renames[getObjectName(*obj)]; ok {
+				obj.Metadata["name"] = base
+			}
+		}
+		rewrite(obj.Spec)
+		rewrite(obj.Data)
+		rewrite(obj.Extra)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// liveFetcher looks up the current state of objects in a cluster.
+//
+// Fetch returns the live object with the identity (apiVersion, kind,
+// namespace, name) of obj in its generic map form, including everything the
+// server adds, or nil when no such object exists. Two implementations exist:
+// apiFetcher talks to a Kubernetes API server configured by a kubeconfig, and
+// fixtureFetcher answers from recorded objects on disk.
+type liveFetcher interface {
+	Fetch(obj K8sObject) (map[string]interface{}, error)
+}
+
+// fixtureFetcher serves live objects recorded in a manifest file or directory,
+// e.g. saved with "kubectl get -o yaml", so live diffs can be reproduced and
+// tested without a cluster. Objects without a namespace are looked up in
+// "default", like getObjectKey treats them.
+type fixtureFetcher struct {
+	objects map[string]map[string]interface{}
+}
+
+// newFixtureFetcher loads the recorded objects of a file or directory.
+func newFixtureFetcher(path string) (*fixtureFetcher, error) {
+	objects, err := parseK8sObjects(path)
+	if err != nil {
+		return nil, err
+	}
+	fetcher := &fixtureFetcher{objects: make(map[string]map[string]interface{})}
+	for _, obj := range objects {
+		fetcher.objects[getObjectKey(obj)] = objectToMap(obj)
+	}
+	return fetcher, nil
+}
+
+// Fetch returns a copy of the recorded object with obj's identity.
+func (f *fixtureFetcher) Fetch(obj K8sObject) (map[string]interface{}, error) {
+	recorded, ok := f.objects[getObjectKey(obj)]
+	if !ok {
+		return nil, nil
+	}
+	return deepCopyValue(recorded).(map[string]interface{}), nil
+}
+
+// runLiveDiff implements "k8s-diff <manifests> --live": every object of the
+// manifests is fetched from the cluster and the live state is diffed against
+// the manifests, so the output shows what applying them would change. Objects
+// that do not exist in the cluster show up as added.
+func runLiveDiff(files []string) {
+	if len(files) != 1 {
+		fmt.Fprintf(os.Stderr, "Error: --live expects exactly 1 manifest file or directory, got %d\n\n", len(files))
+		fmt.Print(helpText)
+		os.Exit(1)
+	}
+	path := files[0]
+	if err := checkFileExists(path); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	desired, err := parseK8sObjects(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing %s: %v\n", path, err)
+		os.Exit(1)
+	}
+
+	fetcher, err := newLiveFetcher()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	live, err := fetchLiveObjects(fetcher, desired)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	writeDiff("live", path, live, desired)
+}
+
+// newLiveFetcher returns the fetcher selected by the options: recorded
+// fixtures with --live-fixtures, the kubeconfig's cluster otherwise.
+func newLiveFetcher() (liveFetcher, error) {
+	if opts.LiveFixtures != "" {
+		return newFixtureFetcher(opts.LiveFixtures)
+	}
+	return newAPIFetcher(opts.Kubeconfig, opts.KubeContext)
+}
+
+// fetchLiveObjects fetches the live counterpart of every desired object and
+// strips it down to the fields the manifests set (see pruneLiveValue).
+func fetchLiveObjects(fetcher liveFetcher, desired []K8sObject) ([]K8sObject, error) {
+	var live []K8sObject
+	for _, obj := range desired {
+		fetched, err := fetcher.Fetch(obj)
+		if err != nil {
+			return nil, fmt.Errorf("fetching %s: %v", getObjectKey(obj), err)
+		}
+		if fetched == nil {
+			continue // Not in the cluster yet
+		}
+
+		pruned := pruneLiveValue(fetched, objectToMap(obj)).(map[string]interface{})
+		liveObj, err := objectFromMap(pruned)
+		if err != nil {
+			return nil, fmt.Errorf("live object %s is invalid: %v", getObjectKey(obj), err)
+		}
+		live = append(live, liveObj)
+	}
+	return live, nil
+}
+
+// pruneLiveValue removes everything from a live value that the desired value
+// does not set: server bookkeeping (uid, resourceVersion, managedFields,
+// creationTimestamp, status, ...), defaulted fields and the last-applied
+// annotation. Only fields the manifests manage are compared, the way a
+// server-side apply would see them.
+//
+// Maps keep only the keys present in the desired map. Lists whose desired
+// items all carry a name (containers, env, ports, volumes) are matched by name,
+// other lists by index; live items without a desired counterpart are kept,
+// since they are real differences rather than defaults.
+func pruneLiveValue(live, desired interface{}) interface{} {
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			return live
+		}
+		for key, val := range l {
+			if want, ok := d[key]; ok {
+				l[key] = pruneLiveValue(val, want)
+			} else {
+				delete(l, key)
+			}
+		}
+		return l
+
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if !ok {
+			return live
+		}
+		byName, names := containersByName(d)
+		named := len(d) > 0 && len(names) == len(d)
+		for i, item := range l {
+			if named {
+				if m, ok := item.(map[string]interface{}); ok {
+					if name, ok := m["name"].(string); ok && byName[name] != nil {
+						l[i] = pruneLiveValue(item, byName[name])
+					}
+				}
+			} else if i < len(d) {
+				l[i] = pruneLiveValue(item, d[i])
+			}
+		}
+		return l
+	}
+	return live
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const liveDesired = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  mode: fast
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: prod
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:1.0
+`
+
+func TestFixtureFetcher(t *testing.T) {
+	path := writeTestFile(t, "live.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  namespace: default
+  uid: 1234
+data:
+  mode: slow
+`)
+	fetcher, err := newFixtureFetcher(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	desired := parseTestObjects(t, liveDesired)
+
+	live, err := fetcher.Fetch(desired[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := live["data"].(map[string]interface{})["mode"]; got != "slow" {
+		t.Errorf("fetched mode = %v, want slow", got)
+	}
+
+	// Fetch must hand out copies, so pruning one cannot change the recording.
+	delete(live["metadata"].(map[string]interface{}), "uid")
+	again, _ := fetcher.Fetch(desired[0])
+	if _, ok := again["metadata"].(map[string]interface{})["uid"]; !ok {
+		t.Error("modifying a fetched object changed the recorded fixture")
+	}
+
+	missing, err := fetcher.Fetch(desired[1])
+	if err != nil || missing != nil {
+		t.Errorf("Fetch of an unrecorded object = %v, %v; want nil, nil", missing, err)
+	}
+}
+
+// newTestAPIServer serves discovery for v1 and apps/v1 plus the given objects
+// by request path. Requests without the expected bearer token are rejected.
+func newTestAPIServer(t *testing.T, objects map[string]string) *httptest.Server {
+	t.Helper()
+	discovery := map[string]string{
+		"/api/v1": `{"resources": [
+			{"name": "configmaps", "kind": "ConfigMap", "namespaced": true},
+			{"name": "namespaces", "kind": "Namespace", "namespaced": false}]}`,
+		"/apis/apps/v1": `{"resources": [
+			{"name": "deployments", "kind": "Deployment", "namespaced": true},
+			{"name": "deployments/scale", "kind": "Scale", "namespaced": true}]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		body, ok := discovery[r.URL.Path]
+		if !ok {
+			body, ok = objects[r.URL.Path]
+		}
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestAPIFetcher points an apiFetcher at server through a kubeconfig whose
+// context defaults to the "team" namespace.
+func newTestAPIFetcher(t *testing.T, server *httptest.Server, token string) *apiFetcher {
+	t.Helper()
+	path := writeTestFile(t, "kubeconfig", fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: test
+clusters:
+- name: test-cluster
+  cluster:
+    server: %s
+contexts:
+- name: test
+  context:
+    cluster: test-cluster
+    user: test-user
+    namespace: team
+users:
+- name: test-user
+  user:
+    token: %s
+`, server.URL, token))
+	fetcher, err := newAPIFetcher(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fetcher
+}
+
+func TestAPIFetcher(t *testing.T) {
+	server := newTestAPIServer(t, map[string]string{
+		"/api/v1/namespaces/team/configmaps/settings": `{"apiVersion": "v1", "kind": "ConfigMap",
+			"metadata": {"name": "settings", "namespace": "team", "resourceVersion": "42"},
+			"data": {"mode": "slow"}}`,
+	})
+	fetcher := newTestAPIFetcher(t, server, "secret-token")
+	desired := parseTestObjects(t, liveDesired)
+
+	// Objects without a namespace are looked up in the context's namespace.
+	live, err := fetcher.Fetch(desired[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := live["data"].(map[string]interface{})["mode"]; got != "slow" {
+		t.Errorf("fetched mode = %v, want slow", got)
+	}
+
+	// A 404 means the object does not exist yet.
+	live, err = fetcher.Fetch(desired[1])
+	if err != nil || live != nil {
+		t.Errorf("Fetch of a missing Deployment = %v, %v; want nil, nil", live, err)
+	}
+	if resource := fetcher.resources["apps/v1"]; len(resource) != 2 {
+		t.Errorf("discovery of apps/v1 was not cached: %v", fetcher.resources)
+	}
+}
+
+func TestAPIFetcherErrors(t *testing.T) {
+	server := newTestAPIServer(t, map[string]string{
+		"/api/v1/namespaces/team/configmaps/settings": `null`,
+	})
+
+	tests := []struct {
+		name     string
+		token    string
+		manifest string
+		want     string
+	}{
+		{
+			name:     "unauthorized",
+			token:    "wrong-token",
+			manifest: liveDesired,
+			want:     "401 Unauthorized",
+		},
+		{
+			name:     "null body",
+			token:    "secret-token",
+			manifest: liveDesired,
+			want:     "expected an object",
+		},
+		{
+			name:     "unknown kind",
+			token:    "secret-token",
+			manifest: "apiVersion: apps/v1\nkind: Scale\nmetadata:\n  name: web\n",
+			want:     "kind Scale is not served",
+		},
+		{
+			name:     "unknown API version",
+			token:    "secret-token",
+			manifest: "apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: w\n",
+			want:     "API version example.com/v1 is not served",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher := newTestAPIFetcher(t, server, tt.token)
+			_, err := fetcher.Fetch(parseTestObjects(t, tt.manifest)[0])
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Fetch error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewAPIFetcherUnknownContext(t *testing.T) {
+	path := writeTestFile(t, "kubeconfig", "current-context: missing\n")
+	if _, err := newAPIFetcher(path, ""); err == nil || !strings.Contains(err.Error(), "context 'missing' not found") {
+		t.Errorf("newAPIFetcher error = %v, want a missing context error", err)
+	}
+}
+
+func TestPruneLiveValue(t *testing.T) {
+	live := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":            "web",
+			"uid":             "1234",
+			"resourceVersion": "42",
+			"annotations": map[string]interface{}{
+				lastAppliedAnnotation: "{}",
+			},
+		},
+		"spec": map[string]interface{}{
+			"replicas":             2,
+			"revisionHistoryLimit": 10,
+			"containers": []interface{}{
+				map[string]interface{}{"name": "sidecar", "image": "proxy:2", "imagePullPolicy": "Always"},
+				map[string]interface{}{"name": "app", "image": "app:1.1", "imagePullPolicy": "IfNotPresent"},
+			},
+			"args": []interface{}{
+				map[string]interface{}{"value": "a", "default": true},
+				map[string]interface{}{"value": "b"},
+			},
+		},
+		"status": map[string]interface{}{"readyReplicas": 2},
+	}
+	desired := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "web"},
+		"spec": map[string]interface{}{
+			"replicas": 3,
+			"containers": []interface{}{
+				map[string]interface{}{"name": "app", "image": "app:1.0"},
+			},
+			"args": []interface{}{
+				map[string]interface{}{"value": "a"},
+			},
+		},
+	}
+	want := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "web"},
+		"spec": map[string]interface{}{
+			"replicas": 2,
+			"containers": []interface{}{
+				// Live items without a desired counterpart are real differences.
+				map[string]interface{}{"name": "sidecar", "image": "proxy:2", "imagePullPolicy": "Always"},
+				map[string]interface{}{"name": "app", "image": "app:1.1"},
+			},
+			"args": []interface{}{
+				map[string]interface{}{"value": "a"},
+				map[string]interface{}{"value": "b"},
+			},
+		},
+	}
+
+	if got := pruneLiveValue(live, desired); !reflect.DeepEqual(got, want) {
+		t.Errorf("pruneLiveValue =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestFetchLiveObjects(t *testing.T) {
+	fetcher, err := newFixtureFetcher(writeTestFile(t, "live.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  resourceVersion: "42"
+data:
+  mode: slow
+  extra: kept-out
+status:
+  phase: Active
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	live, err := fetchLiveObjects(fetcher, parseTestObjects(t, liveDesired))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The Deployment is not recorded and is left out; the ConfigMap is pruned
+	// down to the fields the manifest sets.
+	if len(live) != 1 {
+		t.Fatalf("fetched %d objects, want 1", len(live))
+	}
+	want := map[string]interface{}{"mode": "slow"}
+	if !reflect.DeepEqual(live[0].Data, want) || len(live[0].Extra) != 0 || live[0].Metadata["resourceVersion"] != nil {
+		t.Errorf("live object not pruned: %+v", live[0])
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// markdownSection is the collapsible field diff of one modified object.
+type markdownSection struct {
+	Body       string
+	Importance int // Higher is kept longer when the report must be truncated
+	Position   int // Original order, used to restore it after ranking
+}
+
+// markdownTableHeader starts the summary table of the Markdown report.
+const markdownTableHeader = "| Object | Kind | Namespace | Change | Field changes | Source |\n|---|---|---|---|---|---|\n"
+
+// writeMarkdownReport renders the diff as a Markdown report for pull request comments.
+//
+// The report starts with a summary table (object, kind, namespace, change type,
+// number of field changes and source file:line), followed by one collapsible
+// <details> section per modified object with its leaf changes (see
+// objectLeafChanges) in a fenced diff block, each followed by its source
+// position. Secret data and last-applied configurations are redacted (see
+// isSensitivePath).
+//
+// The report is kept under opts.MaxBytes: when it is too large, the least
+// important object sections are dropped first (see sectionImportance), and
+// as a last resort the summary table itself is cut short.
+func writeMarkdownReport(w io.Writer, objects1, objects2 []K8sObject) {
+	var rows []string
+	var sections []markdownSection
+	counts := make(map[string]int)
+
+	for _, pair := range matchObjects(objects1, objects2) {
+		status := pairStatus(pair)
+		counts[status]++
+		if status == "unchanged" {
+			continue
+		}
+
+		obj := pair.New
+		if obj == nil {
+			obj = pair.Old
+		}
+
+		fieldCount := "-"
+		if status == "modified" {
+			changes := objectLeafChanges(*pair.Old, *pair.New)
+			fieldCount = fmt.Sprintf("%d", len(changes))
+			sections = append(sections, markdownSection{
+				Body:       markdownObjectSection(pair, changes),
+				Importance: sectionImportance(changes),
+				Position:   len(sections),
+			})
+		}
+
+		namespace := getObjectNamespace(*obj)
+		if namespace == "" {
+			namespace = "-"
+		}
+		source := "-"
+		if pos, ok := pairSource(pair); ok {
+			source = "`" + markdownEscape(pos.String()) + "`"
+		}
+		change := status
+		if pair.RenamedFrom != "" {
+			change = fmt.Sprintf("renamed from `%s` (%d%%)", markdownEscape(pair.RenamedFrom), pair.Similarity)
+		}
+		rows = append(rows, fmt.Sprintf("| `%s` | %s | %s | %s | %s | %s |",
+			markdownEscape(getObjectName(*obj)), markdownEscape(obj.Kind), markdownEscape(namespace), change, fieldCount, source))
+	}
+
+	var header strings.Builder
+	header.WriteString("## k8s-diff report\n\n")
+	if len(rows) == 0 {
+		header.WriteString("No changes.\n")
+		fmt.Fprint(w, header.String())
+		return
+	}
+	fmt.Fprintf(&header, "**%d added, %d removed, %d modified, %d unchanged**\n\n",
+		counts["added"], counts["removed"], counts["modified"], counts["unchanged"])
+
+	fmt.Fprint(w, fitMarkdownReport(header.String(), rows, sections, opts.MaxBytes))
+}
+
+// markdownObjectSection renders the collapsible field diff of one object.
+// Field changes use a "diff" code block so GitHub and GitLab color them.
+func markdownObjectSection(pair objectPair, changes []fieldChange) string {
+	key, obj := pair.Key, *pair.New
+	var b strings.Builder
+	plural := "s"
+	if len(changes) == 1 {
+		plural = ""
+	}
+	title := fmt.Sprintf("<code>%s</code> (%d field change%s)", htmlEscape(key), len(changes), plural)
+	if pair.RenamedFrom != "" {
+		title = fmt.Sprintf("<code>%s</code> &rarr; <code>%s</code> (renamed, %d%% similar; %d field change%s)",
+			htmlEscape(pair.RenamedFrom), htmlEscape(key), pair.Similarity, len(changes), plural)
+	}
+	fmt.Fprintf(&b, "<details>\n<summary>%s</summary>\n\n", title)
+	b.WriteString("```diff\n")
+	for _, change := range changes {
+		redact := isSensitivePath(obj, change.Path)
+		line := formatChange(change, redact)
+		if change.Type == changeModified {
+			// Split modifications into -/+ lines so they are highlighted
+			oldText, newText := flatValue(change.Old), flatValue(change.New)
+			if redact {
+				oldText, newText = redactedValue, redactedValue
+			}
+			line = fmt.Sprintf("- %s: %s\n+ %s: %s", change.Path, oldText, change.Path, newText)
+		}
+		if pos, ok := changeSource(pair, change); ok {
+			line += "  # " + pos.String()
+		}
+		b.WriteString(strings.ReplaceAll(line, "```", "'''") + "\n")
+	}
+	b.WriteString("```\n\n</details>\n\n")
+	return b.String()
+}
+
+// sectionImportance ranks an object section for truncation. Changes under
+// spec and data count double; metadata churn and type-only changes count
+// little, so label-only or formatting-only sections are dropped first.
+func sectionImportance(changes []fieldChange) int {
+	importance := 0
+	for _, change := range changes {
+		switch {
+		case change.Type == changeTypeOnly:
+			// Cosmetic - contributes nothing
+		case len(change.Path) > 0 && change.Path[0].Key == "metadata":
+			importance++
+		default:
+			importance += 2
+		}
+	}
+	return importance
+}
+
+// fitMarkdownReport assembles the report and enforces the size limit.
+// Sections are dropped in order of increasing importance (later objects first
+// on ties), and a note records how much was omitted. A limit of 0 disables truncation.
+func fitMarkdownReport(header string, rows []string, sections []markdownSection, limit int) string {
+	table := markdownTableHeader + strings.Join(rows, "\n") + "\n\n"
+
+	kept := append([]markdownSection(nil), sections...)
+	omitted := 0
+	for {
+		report := header + table + joinSections(kept)
+		if omitted > 0 {
+			report += fmt.Sprintf("_%d object section(s) omitted to stay under the %d byte limit._\n", omitted, limit)
+		}
+		if limit <= 0 || len(report) <= limit {
+			return report
+		}
+		if len(kept) == 0 {
+			break
+		}
+
+		// Drop the least important remaining section
+		sort.SliceStable(kept, func(i, j int) bool {
+			if kept[i].Importance != kept[j].Importance {
+				return kept[i].Importance > kept[j].Importance
+			}
+			return kept[i].Position < kept[j].Position
+		})
+		kept = kept[:len(kept)-1]
+		omitted++
+	}
+
+	// Even the bare summary is too large - cut table rows from the end
+	for n := len(rows) - 1; n >= 0; n-- {
+		note := fmt.Sprintf("_Report truncated: %d of %d rows omitted to stay under the %d byte limit._\n", len(rows)-n, len(rows), limit)
+		report := header + markdownTableHeader + strings.Join(rows[:n], "\n") + "\n\n" + note
+		if len(report) <= limit || n == 0 {
+			return report
+		}
+	}
+	return header
+}
+
+// joinSections concatenates sections in their original order.
+func joinSections(sections []markdownSection) string {
+	ordered := append([]markdownSection(nil), sections...)
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].Position < ordered[j].Position })
+
+	var b strings.Builder
+	for _, section := range ordered {
+		b.WriteString(section.Body)
+	}
+	return b.String()
+}
+
+// markdownEscape escapes characters that would break a Markdown table cell.
+func markdownEscape(s string) string {
+	return strings.NewReplacer("|", "\\|", "`", "'", "\n", " ").Replace(s)
+}
+
+// htmlEscape escapes text embedded in raw HTML such as <summary> elements.
+func htmlEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\"", "&quot;").Replace(s)
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// mergeConflict is a field changed differently on both sides of a three-way merge.
+// A missing value (field or object deleted) is recorded with its Has flag unset.
+type mergeConflict struct {
+	Key       string
+	Path      fieldPath
+	Base      interface{}
+	Ours      interface{}
+	Theirs    interface{}
+	HasBase   bool
+	HasOurs   bool
+	HasTheirs bool
+}
+
+// runMergeCommand implements "k8s-diff merge <base> <ours> <theirs>".
+//
+// Objects are matched by identity across all three files, and each object is
+// merged field by field relative to the common base: a change made on only one
+// side is taken as is, identical changes on both sides are taken once, and
+// containers are matched by name so both sides can edit different containers.
+// When nothing conflicts the merged manifests are printed to stdout; otherwise
+// a conflict report listing base/ours/theirs values per field path is printed
+// instead and the command exits with status 1.
+func runMergeCommand(args []string) {
+	if len(args) != 3 {
+		fmt.Fprintf(os.Stderr, "Error: merge expects exactly 3 arguments (<base> <ours> <theirs>), got %d\n\n", len(args))
+		fmt.Print(helpText)
+		os.Exit(1)
+	}
+
+	var sets [3][]K8sObject
+	for i, file := range args {
+		if err := checkFileExists(file); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		objects, err := parseK8sObjects(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing %s: %v\n", file, err)
+			os.Exit(1)
+		}
+		sets[i] = objects
+	}
+
+	merged, conflicts, err := mergeObjects(sets[0], sets[1], sets[2])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(conflicts) > 0 {
+		writeConflictReport(conflicts)
+		os.Exit(1)
+	}
+
+	for i, obj := range merged {
+		if i > 0 {
+			fmt.Println("---")
+		}
+		fmt.Println(strings.Join(normalizedYAML(obj), "\n"))
+	}
+}
+
+// mergeObjects performs the three-way merge of whole manifest sets.
+// The merged objects are ordered like the base, followed by objects added on
+// our side and then objects added on their side. Objects are merged as complete
+// documents, so top-level fields such as RBAC rules or a Secret's type take
+// part in the merge like spec and data.
+func mergeObjects(base, ours, theirs []K8sObject) ([]K8sObject, []mergeConflict, error) {
+	index := func(objects []K8sObject) (map[string]K8sObject, []string) {
+		byKey := make(map[string]K8sObject)
+		var keys []string
+		for _, obj := range objects {
+			key := getObjectKey(obj)
+			if _, seen := byKey[key]; !seen {
+				keys = append(keys, key)
+			}
+			byKey[key] = obj
+		}
+		return byKey, keys
+	}
+	baseByKey, baseKeys := index(base)
+	oursByKey, oursKeys := index(ours)
+	theirsByKey, theirsKeys := index(theirs)
+
+	var merged []K8sObject
+	var conflicts []mergeConflict
+
+	for _, key := range appendMissing(appendMissing(baseKeys, oursKeys), theirsKeys) {
+		b, hasB := baseByKey[key]
+		o, hasO := oursByKey[key]
+		t, hasT := theirsByKey[key]
+
+		var bMap, oMap, tMap interface{}
+		if hasB {
+			bMap = objectToMap(b)
+		}
+		if hasO {
+			oMap = objectToMap(o)
+		}
+		if hasT {
+			tMap = objectToMap(t)
+		}
+
+		result, hasResult, objectConflicts := mergeValues(key, nil, bMap, hasB, oMap, hasO, tMap, hasT)
+		if len(objectConflicts) > 0 {
+			conflicts = append(conflicts, objectConflicts...)
+			continue
+		}
+		if !hasResult {
+			continue // Deleted by one side and untouched by the other, or by both
+		}
+
+		obj, err := objectFromMap(result.(map[string]interface{}))
+		if err != nil {
+			return nil, nil, fmt.Errorf("merged object %s is invalid: %v", key, err)
+		}
+		merged = append(merged, obj)
+	}
+
+	return merged, conflicts, nil
+}
+
+// mergeValues merges one value three ways. Each has flag reports whether the
+// value exists on that side. Returns the merged value, whether it exists,
+// and any conflicts found at or below path.
+//
+//   - Both sides agree: their common value wins
+//   - Only one side differs from base: that side wins
+//   - Both sides changed maps: keys are merged recursively
+//   - Both sides changed container arrays: containers are merged by name
+//   - Anything else is a conflict at path
+func mergeValues(key string, path fieldPath, base interface{}, hasBase bool, ours interface{}, hasOurs bool, theirs interface{}, hasTheirs bool) (interface{}, bool, []mergeConflict) {
+	same := func(v1 interface{}, has1 bool, v2 interface{}, has2 bool) bool {
+		return has1 == has2 && (!has1 || reflect.DeepEqual(v1, v2))
+	}
+
+	switch {
+	case same(ours, hasOurs, theirs, hasTheirs):
+		return ours, hasOurs, nil
+	case same(base, hasBase, ours, hasOurs):
+		return theirs, hasTheirs, nil
+	case same(base, hasBase, theirs, hasTheirs):
+		return ours, hasOurs, nil
+	}
+
+	// Both sides changed the value differently - try to merge structurally
+	if hasOurs && hasTheirs {
+		baseMap, baseIsMap := base.(map[string]interface{})
+		oursMap, oursIsMap := ours.(map[string]interface{})
+		theirsMap, theirsIsMap := theirs.(map[string]interface{})
+		if oursIsMap && theirsIsMap && (baseIsMap || !hasBase) {
+			return mergeMaps(key, path, baseMap, oursMap, theirsMap)
+		}
+
+		baseList, baseIsList := base.([]interface{})
+		oursList, oursIsList := ours.([]interface{})
+		theirsList, theirsIsList := theirs.([]interface{})
+		if oursIsList && theirsIsList && (baseIsList || !hasBase) &&
+			isContainerArray(oursList) && isContainerArray(theirsList) && (len(baseList) == 0 || isContainerArray(baseList)) {
+			return mergeContainerLists(key, path, baseList, oursList, theirsList)
+		}
+	}
+
+	conflict := mergeConflict{
+		Key: key, Path: path,
+		Base: base, Ours: ours, Theirs: theirs,
+		HasBase: hasBase, HasOurs: hasOurs, HasTheirs: hasTheirs,
+	}
+	return ours, hasOurs, []mergeConflict{conflict}
+}
+
+// mergeMaps merges two changed maps key by key against their base.
+func mergeMaps(key string, path fieldPath, base, ours, theirs map[string]interface{}) (interface{}, bool, []mergeConflict) {
+	result := make(map[string]interface{})
+	var conflicts []mergeConflict
+
+	for _, field := range unionKeys(unionMap(base, ours), theirs) {
+		b, hasB := base[field]
+		o, hasO := ours[field]
+		t, hasT := theirs[field]
+		val, has, fieldConflicts := mergeValues(key, path.child(keySegment(field)), b, hasB, o, hasO, t, hasT)
+		conflicts = append(conflicts, fieldConflicts...)
+		if has {
+			result[field] = val
+		}
+	}
+
+	return result, true, conflicts
+}
+
+// mergeContainerLists merges two changed container arrays by container name.
+// The merged array follows our container order, with containers added only on
+// their side appended in their order.
+func mergeContainerLists(key string, path fieldPath, base, ours, theirs []interface{}) (interface{}, bool, []mergeConflict) {
+	baseByName, _ := containersByName(base)
+	oursByName, oursNames := containersByName(ours)
+	theirsByName, theirsNames := containersByName(theirs)
+
+	var result []interface{}
+	var conflicts []mergeConflict
+
+	for _, name := range appendMissing(oursNames, theirsNames) {
+		b, hasB := baseByName[name]
+		o, hasO := oursByName[name]
+		t, hasT := theirsByName[name]
+		val, has, itemConflicts := mergeValues(key, path.child(itemSegment(name, -1, -1)), b, hasB, o, hasO, t, hasT)
+		conflicts = append(conflicts, itemConflicts...)
+		if has {
+			result = append(result, val)
+		}
+	}
+
+	return result, true, conflicts
+}
+
+// unionMap returns a map holding the keys of both maps (values from map2 win).
+// Only the key set matters to callers.
+func unionMap(map1, map2 map[string]interface{}) map[string]interface{} {
+	union := make(map[string]interface{}, len(map1)+len(map2))
+	for key, val := range map1 {
+		union[key] = val
+	}
+	for key, val := range map2 {
+		union[key] = val
+	}
+	return union
+}
+
+// writeConflictReport prints every merge conflict with the base, ours and
+// theirs values, grouped by object.
+func writeConflictReport(conflicts []mergeConflict) {
+	fmt.Printf("%s%d merge conflict(s):%s\n", ColorRed, len(conflicts), ColorReset)
+
+	lastKey := ""
+	for _, conflict := range conflicts {
+		if conflict.Key != lastKey {
+			fmt.Printf("\n%s:\n", conflict.Key)
+			lastKey = conflict.Key
+		}
+
+		path := conflict.Path.String()
+		if path == "" {
+			path = "(entire object)"
+		}
+		fmt.Printf("  %s! %s%s\n", ColorRed, path, ColorReset)
+		fmt.Printf("    base:   %s\n", describeMergeValue(conflict.Base, conflict.HasBase))
+		fmt.Printf("    %sours:   %s%s\n", ColorYellow, describeMergeValue(conflict.Ours, conflict.HasOurs), ColorReset)
+		fmt.Printf("    %stheirs: %s%s\n", ColorYellow, describeMergeValue(conflict.Theirs, conflict.HasTheirs), ColorReset)
+	}
+}
+
+// describeMergeValue formats one side of a conflict, naming absent values.
+func describeMergeValue(val interface{}, exists bool) string {
+	if !exists {
+		return "(absent)"
+	}
+	return formatValue(val)
+}
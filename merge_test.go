@@ -0,0 +1,289 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+const mergeBase = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:1.0
+      - name: sidecar
+        image: proxy:1
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  mode: fast
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: reader
+rules:
+- apiGroups: [""]
+  resources: [pods]
+  verbs: [get]
+`
+
+func TestMergeObjectsClean(t *testing.T) {
+	// Ours bumps the app image, adds a label and a Service; theirs edits the
+	// sidecar, scales up, removes the ConfigMap and changes the Role's rules.
+	ours := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels: {tier: frontend}
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:1.1
+      - name: sidecar
+        image: proxy:1
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  mode: fast
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: reader
+rules:
+- apiGroups: [""]
+  resources: [pods]
+  verbs: [get]
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+`
+	theirs := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:1.0
+      - name: sidecar
+        image: proxy:2
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: reader
+rules:
+- apiGroups: [""]
+  resources: [pods]
+  verbs: [get, list]
+`
+	want := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels: {tier: frontend}
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:1.1
+      - name: sidecar
+        image: proxy:2
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: reader
+rules:
+- apiGroups: [""]
+  resources: [pods]
+  verbs: [get, list]
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+`
+
+	merged, conflicts, err := mergeObjects(parseTestObjects(t, mergeBase), parseTestObjects(t, ours), parseTestObjects(t, theirs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) > 0 {
+		t.Fatalf("unexpected conflicts: %+v", conflicts)
+	}
+
+	var got, expected []map[string]interface{}
+	for _, obj := range merged {
+		got = append(got, objectToMap(obj))
+	}
+	for _, obj := range parseTestObjects(t, want) {
+		expected = append(expected, objectToMap(obj))
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("merged objects =\n%v\nwant\n%v", got, expected)
+	}
+}
+
+func TestMergeObjectsConflicts(t *testing.T) {
+	tests := []struct {
+		name         string
+		ours, theirs string
+		key, path    string
+		hasOurs      bool
+		hasTheirs    bool
+		oursValue    interface{}
+		theirsValue  interface{}
+	}{
+		{
+			name:        "same scalar changed differently",
+			ours:        "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\ndata:\n  mode: slow\n",
+			theirs:      "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\ndata:\n  mode: medium\n",
+			key:         "ConfigMap/settings",
+			path:        "data.mode",
+			hasOurs:     true,
+			hasTheirs:   true,
+			oursValue:   "slow",
+			theirsValue: "medium",
+		},
+		{
+			name:        "field removed on one side and changed on the other",
+			ours:        "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\ndata: {}\n",
+			theirs:      "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\ndata:\n  mode: medium\n",
+			key:         "ConfigMap/settings",
+			path:        "data.mode",
+			hasTheirs:   true,
+			theirsValue: "medium",
+		},
+		{
+			name:      "object deleted on one side and modified on the other",
+			ours:      "",
+			theirs:    "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\ndata:\n  mode: medium\n",
+			key:       "ConfigMap/settings",
+			path:      "",
+			hasTheirs: true,
+		},
+		{
+			name: "container image changed on both sides",
+			ours: `apiVersion: apps/v1
+kind: Deployment
+metadata: {name: web}
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - {name: app, image: app:1.1}
+      - {name: sidecar, image: proxy:1}
+`,
+			theirs: `apiVersion: apps/v1
+kind: Deployment
+metadata: {name: web}
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - {name: app, image: app:2.0}
+      - {name: sidecar, image: proxy:1}
+`,
+			key:         "Deployment/web",
+			path:        "spec.template.spec.containers[name=app].image",
+			hasOurs:     true,
+			hasTheirs:   true,
+			oursValue:   "app:1.1",
+			theirsValue: "app:2.0",
+		},
+		{
+			name: "rules changed on both sides",
+			ours: `apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata: {name: reader}
+rules: []
+`,
+			theirs: `apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata: {name: reader}
+rules:
+- {apiGroups: [""], resources: [pods], verbs: [list]}
+`,
+			key:       "Role/reader",
+			path:      "rules",
+			hasOurs:   true,
+			hasTheirs: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := parseTestObjects(t, mergeBase)
+			var ours, theirs []K8sObject
+			// Objects not mentioned by a side are kept unchanged there.
+			for _, obj := range base {
+				if key := getObjectKey(obj); key != tt.key {
+					ours = append(ours, obj)
+					theirs = append(theirs, obj)
+				}
+			}
+			if tt.ours != "" {
+				ours = append(ours, parseTestObjects(t, tt.ours)...)
+			}
+			theirs = append(theirs, parseTestObjects(t, tt.theirs)...)
+
+			merged, conflicts, err := mergeObjects(base, ours, theirs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(conflicts) != 1 {
+				t.Fatalf("got %d conflicts, want 1: %+v", len(conflicts), conflicts)
+			}
+			conflict := conflicts[0]
+			if conflict.Key != tt.key || conflict.Path.String() != tt.path {
+				t.Errorf("conflict at %s %q, want %s %q", conflict.Key, conflict.Path, tt.key, tt.path)
+			}
+			if !conflict.HasBase || conflict.HasOurs != tt.hasOurs || conflict.HasTheirs != tt.hasTheirs {
+				t.Errorf("conflict has base/ours/theirs = %v/%v/%v, want true/%v/%v",
+					conflict.HasBase, conflict.HasOurs, conflict.HasTheirs, tt.hasOurs, tt.hasTheirs)
+			}
+			if tt.oursValue != nil && conflict.Ours != tt.oursValue {
+				t.Errorf("conflict ours = %v, want %v", conflict.Ours, tt.oursValue)
+			}
+			if tt.theirsValue != nil && conflict.Theirs != tt.theirsValue {
+				t.Errorf("conflict theirs = %v, want %v", conflict.Theirs, tt.theirsValue)
+			}
+
+			// Conflicting objects are left out of the merge; the rest is kept.
+			for _, obj := range merged {
+				if getObjectKey(obj) == tt.key {
+					t.Errorf("conflicting object %s was merged", tt.key)
+				}
+			}
+			if len(merged) != len(base)-1 {
+				t.Errorf("merged %d objects, want %d", len(merged), len(base)-1)
+			}
+		})
+	}
+}
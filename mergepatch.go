@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// strategicMergeKeys maps list field names to the key Kubernetes uses to merge
+// their items in a strategic merge patch (the patchMergeKey of the built-in
+// API types). Lists that are not listed here are replaced as a whole.
+var strategicMergeKeys = map[string]string{
+	"containers":          "name",
+	"initContainers":      "name",
+	"ephemeralContainers": "name",
+	"env":                 "name",
+	"volumes":             "name",
+	"volumeMounts":        "mountPath",
+	"volumeDevices":       "devicePath",
+	"imagePullSecrets":    "name",
+	"hostAliases":         "ip",
+	"ports":               "containerPort", // Service ports use "port", see mergeKeyFor
+	"conditions":          "type",
+	"ownerReferences":     "uid",
+}
+
+// strategicPrimitiveLists names lists of scalars that Kubernetes merges as sets.
+// Removed entries are expressed with a $deleteFromPrimitiveList directive.
+var strategicPrimitiveLists = map[string]bool{
+	"finalizers": true,
+}
+
+// writeStrategicMergePatches prints a minimal strategic merge patch for every
+// changed object as a multi-document YAML stream, ready to use as a kustomize
+// overlay (patchesStrategicMerge or patches).
+//
+// Each patch carries the object's apiVersion, kind, name and namespace so
+// kustomize can find its target. Lists with a known merge key are patched
+// item by item, removed items become "$patch: delete" entries, reordering is
+// recorded with $setElementOrder, and removed set entries use
+// $deleteFromPrimitiveList. Removed objects become "$patch: delete" documents.
+// Added objects cannot be expressed as patches and must be added as resources.
+func writeStrategicMergePatches(w io.Writer, objects1, objects2 []K8sObject) error {
+	var docs []patchDocument
+	for _, pair := range matchObjects(objects1, objects2) {
+		switch pairStatus(pair) {
+		case "modified":
+			patch := strategicMergePatch(pair.New.Kind, nil, objectToMap(*pair.Old), objectToMap(*pair.New))
+			docs = append(docs, newPatchDocument(withPatchTarget(patch, *pair.New), pair))
+		case "removed":
+			docs = append(docs, newPatchDocument(withPatchTarget(map[string]interface{}{"$patch": "delete"}, *pair.Old), pair))
+		}
+	}
+	return writePatchDocuments(w, docs)
+}
+
+// writeJSONMergePatches prints an RFC 7386 JSON merge patch for every modified
+// object as a multi-document YAML stream. Unlike strategic merge patches, merge
+// patches need no schema knowledge - removed fields are set to null and changed
+// lists are replaced as a whole - so they also work for custom resources.
+func writeJSONMergePatches(w io.Writer, objects1, objects2 []K8sObject) error {
+	var docs []patchDocument
+	for _, pair := range matchObjects(objects1, objects2) {
+		if pairStatus(pair) != "modified" {
+			continue
+		}
+		patch := jsonMergePatch(objectToMap(*pair.Old), objectToMap(*pair.New))
+		docs = append(docs, newPatchDocument(withPatchTarget(patch, *pair.New), pair))
+	}
+	return writePatchDocuments(w, docs)
+}
+
+// withPatchTarget adds the identifying fields kustomize uses to find the
+// object a patch applies to: apiVersion, kind, metadata.name and metadata.namespace.
+func withPatchTarget(patch map[string]interface{}, obj K8sObject) map[string]interface{} {
+	patch["apiVersion"] = obj.APIVersion
+	patch["kind"] = obj.Kind
+
+	metadata, _ := patch["metadata"].(map[string]interface{})
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+	metadata["name"] = getObjectName(obj)
+	if namespace := getObjectNamespace(obj); namespace != "" {
+		metadata["namespace"] = namespace
+	}
+	patch["metadata"] = metadata
+	return patch
+}
+
+// patchDocument is one patch of a multi-document patch stream, with the
+// source position of the object it was generated from ("" when unknown).
+type patchDocument struct {
+	Body   map[string]interface{}
+	Source string
+}
+
+// newPatchDocument pairs a patch body with the position of its object.
+func newPatchDocument(body map[string]interface{}, pair objectPair) patchDocument {
+	doc := patchDocument{Body: body}
+	if pos, ok := pairSource(pair); ok {
+		doc.Source = pos.String()
+	}
+	return doc
+}
+
+// writePatchDocuments encodes patches as YAML documents separated by "---".
+// Each document is preceded by a "# Source: file:line" comment when known.
+func writePatchDocuments(w io.Writer, docs []patchDocument) error {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	for _, doc := range docs {
+		var node yaml.Node
+		if err := node.Encode(doc.Body); err != nil {
+			return err
+		}
+		if doc.Source != "" {
+			node.HeadComment = "Source: " + doc.Source
+		}
+		if err := encoder.Encode(&node); err != nil {
+			return err
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// jsonMergePatch builds an RFC 7386 merge patch turning map1 into map2:
+// removed keys map to null, nested maps are patched recursively and every
+// other changed value (including lists) is replaced.
+func jsonMergePatch(map1, map2 map[string]interface{}) map[string]interface{} {
+	patch := make(map[string]interface{})
+	for _, key := range unionKeys(map1, map2) {
+		val1, has1 := map1[key]
+		val2, has2 := map2[key]
+		switch {
+		case !has2:
+			patch[key] = nil
+		case !has1:
+			patch[key] = val2
+		case reflect.DeepEqual(val1, val2):
+			// Unchanged - omitted from a minimal patch
+		default:
+			sub1, ok1 := val1.(map[string]interface{})
+			sub2, ok2 := val2.(map[string]interface{})
+			if ok1 && ok2 {
+				patch[key] = jsonMergePatch(sub1, sub2)
+			} else {
+				patch[key] = val2
+			}
+		}
+	}
+	return patch
+}
+
+// strategicMergePatch builds a minimal strategic merge patch turning map1 into
+// map2. The path holds the map keys leading to this map and is used to pick
+// merge keys for nested lists.
+func strategicMergePatch(kind string, path []string, map1, map2 map[string]interface{}) map[string]interface{} {
+	patch := make(map[string]interface{})
+	for _, key := range unionKeys(map1, map2) {
+		val1, has1 := map1[key]
+		val2, has2 := map2[key]
+		switch {
+		case !has2:
+			patch[key] = nil
+		case !has1:
+			patch[key] = val2
+		case reflect.DeepEqual(val1, val2):
+			// Unchanged - omitted from a minimal patch
+		default:
+			strategicMergeField(patch, kind, append(path[:len(path):len(path)], key), key, val1, val2)
+		}
+	}
+	return patch
+}
+
+// strategicMergeField records the patch for one changed field in patch.
+func strategicMergeField(patch map[string]interface{}, kind string, path []string, key string, val1, val2 interface{}) {
+	switch v1 := val1.(type) {
+	case map[string]interface{}:
+		if v2, ok := val2.(map[string]interface{}); ok {
+			patch[key] = strategicMergePatch(kind, path, v1, v2)
+			return
+		}
+	case []interface{}:
+		v2, ok := val2.([]interface{})
+		if !ok {
+			break
+		}
+		if mergeKey := mergeKeyFor(kind, path); mergeKey != "" && hasMergeKey(v1, mergeKey) && hasMergeKey(v2, mergeKey) {
+			items, order := strategicMergeList(kind, path, mergeKey, v1, v2)
+			if len(items) > 0 {
+				patch[key] = items
+			}
+			if order != nil {
+				patch["$setElementOrder/"+key] = order
+			}
+			return
+		}
+		if strategicPrimitiveLists[key] && isScalarList(v1) && isScalarList(v2) {
+			added, removed := scalarListDelta(v1, v2)
+			if len(added) > 0 {
+				patch[key] = added
+			}
+			if len(removed) > 0 {
+				patch["$deleteFromPrimitiveList/"+key] = removed
+			}
+			return
+		}
+	}
+
+	// Scalars, atomic lists and type changes replace the whole value
+	patch[key] = val2
+}
+
+// strategicMergeList patches a list whose items are identified by mergeKey.
+//
+// Returns the patch items - new items in full, changed items as a nested patch
+// that includes the merge key, removed items as {mergeKey: ..., $patch: delete} -
+// and, when the merged result would not already be in the new order, the
+// $setElementOrder list that restores it.
+func strategicMergeList(kind string, path []string, mergeKey string, list1, list2 []interface{}) ([]interface{}, []interface{}) {
+	items1, keys1 := itemsByMergeKey(list1, mergeKey)
+	items2, keys2 := itemsByMergeKey(list2, mergeKey)
+
+	var patchItems []interface{}
+	for _, key := range keys2 {
+		item2 := items2[key]
+		item1, existed := items1[key]
+		switch {
+		case !existed:
+			patchItems = append(patchItems, item2)
+		case !reflect.DeepEqual(item1, item2):
+			map1, ok1 := item1.(map[string]interface{})
+			map2, ok2 := item2.(map[string]interface{})
+			if !ok1 || !ok2 {
+				patchItems = append(patchItems, item2)
+				continue
+			}
+			itemPatch := strategicMergePatch(kind, path, map1, map2)
+			itemPatch[mergeKey] = map2[mergeKey]
+			patchItems = append(patchItems, itemPatch)
+		}
+	}
+	for _, key := range keys1 {
+		if _, kept := items2[key]; !kept {
+			item := items1[key].(map[string]interface{})
+			patchItems = append(patchItems, map[string]interface{}{mergeKey: item[mergeKey], "$patch": "delete"})
+		}
+	}
+
+	// Merging keeps existing items in place and appends new ones; only record
+	// an explicit order when that would differ from the new list
+	var merged []string
+	for _, key := range keys1 {
+		if _, kept := items2[key]; kept {
+			merged = append(merged, key)
+		}
+	}
+	for _, key := range keys2 {
+		if _, existed := items1[key]; !existed {
+			merged = append(merged, key)
+		}
+	}
+	if reflect.DeepEqual(merged, keys2) {
+		return patchItems, nil
+	}
+
+	var order []interface{}
+	for _, key := range keys2 {
+		order = append(order, map[string]interface{}{mergeKey: items2[key].(map[string]interface{})[mergeKey]})
+	}
+	return patchItems, order
+}
+
+// mergeKeyFor returns the strategic merge key for the list at path, or "" when
+// the list is atomic. Service ports are merged by "port" rather than the
+// "containerPort" used for container ports.
+func mergeKeyFor(kind string, path []string) string {
+	field := path[len(path)-1]
+	if field == "ports" && kind == "Service" && strings.Join(path, ".") == "spec.ports" {
+		return "port"
+	}
+	return strategicMergeKeys[field]
+}
+
+// hasMergeKey reports whether every list item is a map carrying mergeKey.
+func hasMergeKey(list []interface{}, mergeKey string) bool {
+	for _, item := range list {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if _, ok := m[mergeKey]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// itemsByMergeKey indexes list items by their merge key value, returning the
+// keys in list order. Values are compared by their formatted text so numeric
+// keys such as containerPort work as map keys.
+func itemsByMergeKey(list []interface{}, mergeKey string) (map[string]interface{}, []string) {
+	items := make(map[string]interface{})
+	var keys []string
+	for _, item := range list {
+		key := fmt.Sprintf("%v", item.(map[string]interface{})[mergeKey])
+		if _, seen := items[key]; !seen {
+			keys = append(keys, key)
+		}
+		items[key] = item
+	}
+	return items, keys
+}
+
+// isScalarList reports whether a list contains no maps or nested lists.
+func isScalarList(list []interface{}) bool {
+	for _, item := range list {
+		if isComposite(item) {
+			return false
+		}
+	}
+	return true
+}
+
+// scalarListDelta returns the entries only in list2 (added) and only in list1 (removed).
+func scalarListDelta(list1, list2 []interface{}) ([]interface{}, []interface{}) {
+	var added, removed []interface{}
+	for _, item := range list2 {
+		if !containsValue(list1, item) {
+			added = append(added, item)
+		}
+	}
+	for _, item := range list1 {
+		if !containsValue(list2, item) {
+			removed = append(removed, item)
+		}
+	}
+	return added, removed
+}
+
+// containsValue reports whether list contains a value deeply equal to item.
+func containsValue(list []interface{}, item interface{}) bool {
+	for _, candidate := range list {
+		if reflect.DeepEqual(candidate, item) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// runPatchCommand implements "k8s-diff patch <base> <changes.json>".
+//
+// It applies a change set exported with --output json onto a manifest set and
+// prints the resulting manifests to stdout. Every change is checked against the
+// base first: removed and modified fields must still hold their recorded old
+// value, added fields and objects must not exist yet, and removed objects must
+// be unchanged. All conflicts are reported together and nothing is printed
+// when any are found, so a stale diff can never be half-applied.
+func runPatchCommand(args []string) {
+	if len(args) != 2 {
+		fmt.Fprintf(os.Stderr, "Error: patch expects exactly 2 arguments (<base> <changes.json>), got %d\n\n", len(args))
+		fmt.Print(helpText)
+		os.Exit(1)
+	}
+	baseFile, changesFile := args[0], args[1]
+
+	for _, file := range []string{baseFile, changesFile} {
+		if err := checkFileExists(file); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	objects, err := parseK8sObjects(baseFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing %s: %v\n", baseFile, err)
+		os.Exit(1)
+	}
+
+	set, err := readChangeSet(changesFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", changesFile, err)
+		os.Exit(1)
+	}
+
+	result, conflicts := applyChangeSet(objects, set)
+	if len(conflicts) > 0 {
+		fmt.Fprintf(os.Stderr, "Error: %d conflict(s) applying %s to %s:\n", len(conflicts), changesFile, baseFile)
+		for _, conflict := range conflicts {
+			fmt.Fprintf(os.Stderr, "  %s\n", conflict)
+		}
+		os.Exit(1)
+	}
+
+	for i, obj := range result {
+		if i > 0 {
+			fmt.Println("---")
+		}
+		fmt.Println(strings.Join(normalizedYAML(obj), "\n"))
+	}
+}
+
+// readChangeSet loads a change set written by --output json. Numbers are
+// decoded as int or float64, matching what the YAML parser produces, so
+// recorded values compare equal to values read from manifests.
+func readChangeSet(filename string) (changeSet, error) {
+	var set changeSet
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return set, err
+	}
+	defer file.Close()
+
+	var raw struct {
+		Objects []struct {
+			objectChangeSet
+			Object  interface{} `json:"object"`
+			Changes []struct {
+				changeRecord
+				Old interface{} `json:"old"`
+				New interface{} `json:"new"`
+			} `json:"changes"`
+		} `json:"objects"`
+	}
+	decoder := json.NewDecoder(file)
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return set, fmt.Errorf("invalid change set: %v", err)
+	}
+
+	for _, entry := range raw.Objects {
+		obj := entry.objectChangeSet
+		if m, ok := normalizeJSONValue(entry.Object).(map[string]interface{}); ok {
+			obj.Object = m
+		}
+		obj.Changes = nil
+		for _, change := range entry.Changes {
+			record := change.changeRecord
+			record.Segments = normalizeJSONValue(record.Segments).([]interface{})
+			record.Old = normalizeJSONValue(change.Old)
+			record.New = normalizeJSONValue(change.New)
+			obj.Changes = append(obj.Changes, record)
+		}
+		set.Objects = append(set.Objects, obj)
+	}
+	return set, nil
+}
+
+// normalizeJSONValue converts json.Number values into int (when integral) or
+// float64, recursively, mirroring how yaml.v3 decodes numbers.
+func normalizeJSONValue(val interface{}) interface{} {
+	switch v := val.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return int(i)
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeJSONValue(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeJSONValue(item)
+		}
+		return v
+	}
+	return val
+}
+
+// applyChangeSet applies a change set to a list of objects and returns the
+// patched objects (base order, with added objects appended) plus a
+// description of every conflict found.
+func applyChangeSet(objects []K8sObject, set changeSet) ([]K8sObject, []string) {
+	var conflicts []string
+	result := append([]K8sObject(nil), objects...)
+
+	indexOfKey := func(key string) int {
+		for i, obj := range result {
+			if getObjectKey(obj) == key {
+				return i
+			}
+		}
+		return -1
+	}
+
+	for _, entry := range set.Objects {
+		i := indexOfKey(entry.Key)
+
+		switch entry.Status {
+		case "added":
+			if i >= 0 {
+				conflicts = append(conflicts, fmt.Sprintf("%s: object to add already exists", entry.Key))
+				continue
+			}
+			obj, err := objectFromMap(entry.Object)
+			if err != nil {
+				conflicts = append(conflicts, fmt.Sprintf("%s: invalid recorded object: %v", entry.Key, err))
+				continue
+			}
+			result = append(result, obj)
+
+		case "removed":
+			if i < 0 {
+				conflicts = append(conflicts, fmt.Sprintf("%s: object to remove does not exist", entry.Key))
+				continue
+			}
+			if !valuesEqual(objectToMap(result[i]), entry.Object) {
+				conflicts = append(conflicts, fmt.Sprintf("%s: object to remove differs from the recorded object", entry.Key))
+				continue
+			}
+			result = append(result[:i], result[i+1:]...)
+
+		case "modified":
+			if i < 0 {
+				conflicts = append(conflicts, fmt.Sprintf("%s: object to modify does not exist", entry.Key))
+				continue
+			}
+			root := deepCopyValue(objectToMap(result[i])).(map[string]interface{})
+			objectConflicts := 0
+			for _, change := range entry.Changes {
+				if _, err := applyChangeAt(root, change.Segments, change); err != nil {
+					conflicts = append(conflicts, fmt.Sprintf("%s %s: %v", entry.Key, change.Path, err))
+					objectConflicts++
+				}
+			}
+			if objectConflicts > 0 {
+				continue
+			}
+			obj, err := objectFromMap(root)
+			if err != nil {
+				conflicts = append(conflicts, fmt.Sprintf("%s: patched object is invalid: %v", entry.Key, err))
+				continue
+			}
+			result[i] = obj
+
+		default:
+			conflicts = append(conflicts, fmt.Sprintf("%s: unknown status '%s'", entry.Key, entry.Status))
+		}
+	}
+
+	return result, conflicts
+}
+
+// applyChangeAt applies one recorded change below node, following segments,
+// and returns the updated node. Arrays are returned rather than modified in
+// place because insertions and removals change their length.
+func applyChangeAt(node interface{}, segments []interface{}, change changeRecord) (interface{}, error) {
+	if len(segments) == 0 {
+		return node, fmt.Errorf("empty path")
+	}
+	seg, last := segments[0], len(segments) == 1
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		key, ok := seg.(string)
+		if !ok {
+			return node, fmt.Errorf("expected a map key, found %v", seg)
+		}
+		current, exists := n[key]
+		if last {
+			newVal, keep, err := applyLeafChange(current, exists, change)
+			if err != nil {
+				return node, err
+			}
+			if keep {
+				n[key] = newVal
+			} else {
+				delete(n, key)
+			}
+			return n, nil
+		}
+		if !exists {
+			return node, fmt.Errorf("path no longer exists (missing '%s')", key)
+		}
+		updated, err := applyChangeAt(current, segments[1:], change)
+		if err != nil {
+			return node, err
+		}
+		n[key] = updated
+		return n, nil
+
+	case []interface{}:
+		index, insertAt, err := resolveArraySegment(n, seg)
+		if err != nil {
+			return node, err
+		}
+		exists := index >= 0
+		if last {
+			var current interface{}
+			if exists {
+				current = n[index]
+			}
+			newVal, keep, err := applyLeafChange(current, exists, change)
+			if err != nil {
+				return node, err
+			}
+			switch {
+			case !exists:
+				insertAt = min(insertAt, len(n))
+				return append(n[:insertAt], append([]interface{}{newVal}, n[insertAt:]...)...), nil
+			case keep:
+				n[index] = newVal
+				return n, nil
+			default:
+				return append(n[:index], n[index+1:]...), nil
+			}
+		}
+		if !exists {
+			return node, fmt.Errorf("path no longer exists (missing item %s)", formatSegment(seg))
+		}
+		updated, err := applyChangeAt(n[index], segments[1:], change)
+		if err != nil {
+			return node, err
+		}
+		n[index] = updated
+		return n, nil
+	}
+
+	return node, fmt.Errorf("path no longer exists (found a scalar at %s)", formatSegment(seg))
+}
+
+// resolveArraySegment finds the array item a segment refers to. Returns the
+// item's index (-1 if absent) and the position to insert it at when adding.
+func resolveArraySegment(list []interface{}, seg interface{}) (int, int, error) {
+	switch s := seg.(type) {
+	case int:
+		if s < 0 {
+			return -1, 0, fmt.Errorf("invalid array index %d", s)
+		}
+		if s < len(list) {
+			return s, s, nil
+		}
+		return -1, s, nil
+	case map[string]interface{}:
+		name, ok := s["name"].(string)
+		if !ok {
+			return -1, 0, fmt.Errorf("invalid name selector %v", s)
+		}
+		insertAt, _ := s["index"].(int)
+		return indexOfContainer(list, name), insertAt, nil
+	}
+	return -1, 0, fmt.Errorf("expected an array index or name selector, found %v", seg)
+}
+
+// applyLeafChange checks a recorded change against the current value of its
+// field and returns the value to store, or keep=false to delete the field.
+func applyLeafChange(current interface{}, exists bool, change changeRecord) (interface{}, bool, error) {
+	switch change.Type {
+	case changeAdded:
+		if exists {
+			return nil, false, fmt.Errorf("field to add already exists with value %s", formatValue(current))
+		}
+		return change.New, true, nil
+	case changeRemoved:
+		if !exists {
+			return nil, false, fmt.Errorf("field to remove does not exist")
+		}
+		if !valuesEqual(current, change.Old) {
+			return nil, false, fmt.Errorf("expected %s, found %s", formatValue(change.Old), formatValue(current))
+		}
+		return nil, false, nil
+	case changeModified, changeTypeOnly:
+		if !exists {
+			return nil, false, fmt.Errorf("field to modify does not exist")
+		}
+		if !valuesEqual(current, change.Old) {
+			return nil, false, fmt.Errorf("expected %s, found %s", formatValue(change.Old), formatValue(current))
+		}
+		return change.New, true, nil
+	}
+	return nil, false, fmt.Errorf("unknown change type '%s'", change.Type)
+}
+
+// valuesEqual compares two values by their canonical JSON encoding, so that
+// numbers compare equal regardless of whether they were decoded as int or float64.
+func valuesEqual(val1, val2 interface{}) bool {
+	json1, err1 := json.Marshal(val1)
+	json2, err2 := json.Marshal(val2)
+	return err1 == nil && err2 == nil && string(json1) == string(json2)
+}
+
+// deepCopyValue copies nested maps and slices so they can be modified without
+// affecting the original object.
+func deepCopyValue(val interface{}) interface{} {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			copied[key] = deepCopyValue(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = deepCopyValue(item)
+		}
+		return copied
+	}
+	return val
+}
+
+// formatSegment renders a serialized path segment for error messages.
+func formatSegment(seg interface{}) string {
+	if s, ok := seg.(map[string]interface{}); ok {
+		return fmt.Sprintf("[name=%v]", s["name"])
+	}
+	return fmt.Sprintf("[%v]", seg)
+}
+
+// objectFromMap converts the generic form produced by objectToMap back into
+// a K8sObject and validates it.
+func objectFromMap(m map[string]interface{}) (K8sObject, error) {
+	var obj K8sObject
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		return obj, err
+	}
+	if err := yaml.Unmarshal(data, &obj); err != nil {
+		return obj, err
+	}
+	return obj, validateK8sObject(obj, 1)
+}
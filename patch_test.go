@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const patchOld = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  mode: fast
+  retries: "3"
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels: {app: web}
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:1.0
+        ports: [{containerPort: 80}]
+      - name: sidecar
+        image: proxy:1
+      - name: logger
+        image: log:1
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: old-credentials
+data:
+  token: c2VjcmV0
+`
+
+const patchNew = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  mode: slow
+  timeout: 30s
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels: {app: web, tier: frontend}
+spec:
+  replicas: "2"
+  template:
+    spec:
+      containers:
+      - name: init
+        image: init:1
+      - name: app
+        image: app:1.1
+        ports: [{containerPort: 80}, {containerPort: 443}]
+      - name: logger
+        image: log:1
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports: [{port: 80}]
+`
+
+// roundTripChangeSet writes the change set between two manifests as JSON and
+// reads it back the way the patch subcommand does.
+func roundTripChangeSet(t *testing.T, objects1, objects2 []K8sObject) changeSet {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := writeChangeSet(&buf, objects1, objects2); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "changes.json")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	set, err := readChangeSet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return set
+}
+
+// objectsByKey maps objects to their generic form, for order-independent
+// comparison.
+func objectsByKey(objects []K8sObject) map[string]map[string]interface{} {
+	byKey := make(map[string]map[string]interface{})
+	for _, obj := range objects {
+		byKey[getObjectKey(obj)] = objectToMap(obj)
+	}
+	return byKey
+}
+
+func TestPatchRoundTrip(t *testing.T) {
+	objects1 := parseTestObjects(t, patchOld)
+	objects2 := parseTestObjects(t, patchNew)
+
+	result, conflicts := applyChangeSet(objects1, roundTripChangeSet(t, objects1, objects2))
+	if len(conflicts) > 0 {
+		t.Fatalf("unexpected conflicts: %v", conflicts)
+	}
+	if got, want := objectsByKey(result), objectsByKey(objects2); !reflect.DeepEqual(got, want) {
+		t.Errorf("patched objects =\n%v\nwant\n%v", got, want)
+	}
+
+	// Applying the same change set again finds the new state and conflicts.
+	_, conflicts = applyChangeSet(result, roundTripChangeSet(t, objects1, objects2))
+	if len(conflicts) == 0 {
+		t.Error("re-applying a change set reported no conflicts")
+	}
+}
+
+func TestPatchConflicts(t *testing.T) {
+	objects1 := parseTestObjects(t, patchOld)
+	objects2 := parseTestObjects(t, patchNew)
+	set := roundTripChangeSet(t, objects1, objects2)
+
+	// A base that drifted from the recorded old values.
+	drifted := parseTestObjects(t, strings.NewReplacer(
+		"mode: fast", "mode: medium",
+		"token: c2VjcmV0", "token: b3RoZXI=",
+	).Replace(patchOld))
+
+	_, conflicts := applyChangeSet(drifted, set)
+	want := []string{
+		"ConfigMap/settings data.mode",
+		"Secret/old-credentials: object to remove differs",
+	}
+	for _, prefix := range want {
+		found := false
+		for _, conflict := range conflicts {
+			found = found || strings.HasPrefix(conflict, prefix)
+		}
+		if !found {
+			t.Errorf("no conflict starting with %q in %q", prefix, conflicts)
+		}
+	}
+	if len(conflicts) != len(want) {
+		t.Errorf("got %d conflicts, want %d: %q", len(conflicts), len(want), conflicts)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// renameCandidate is a possible pairing of a removed and an added object.
+type renameCandidate struct {
+	Removed    int // Index of the removed object's pair
+	Added      int // Index of the added object's pair
+	Similarity int // Percentage of shared field values
+	HashSuffix bool
+}
+
+// detectRenames pairs removed objects with added objects of the same kind that
+// are similar enough to be the same object under a new name or namespace.
+//
+// Candidates whose names differ only by a kustomize hash suffix (see
+// stripHashSuffix) are paired first regardless of similarity. With a threshold
+// above 0 the rest are paired greedily from the most similar down, as long as
+// their similarity reaches threshold percent. A renamed pair replaces the removal in place
+// and the addition is dropped, so pair order stays deterministic.
+func detectRenames(pairs []objectPair, threshold int) []objectPair {
+	var candidates []renameCandidate
+	for i, removed := range pairs {
+		if removed.New != nil {
+			continue
+		}
+		for j, added := range pairs {
+			if added.Old != nil || added.New.Kind != removed.Old.Kind {
+				continue
+			}
+			candidate := renameCandidate{
+				Removed:    i,
+				Added:      j,
+				Similarity: objectSimilarity(*removed.Old, *added.New),
+				HashSuffix: sameHashedName(getObjectName(*removed.Old), getObjectName(*added.New)),
+			}
+			if candidate.HashSuffix || (threshold > 0 && candidate.Similarity >= threshold) {
+				candidates = append(candidates, candidate)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return pairs
+	}
+
+	sort.SliceStable(candidates, func(a, b int) bool {
+		if candidates[a].HashSuffix != candidates[b].HashSuffix {
+			return candidates[a].HashSuffix
+		}
+		return candidates[a].Similarity > candidates[b].Similarity
+	})
+
+	paired := make(map[int]bool)
+	for _, candidate := range candidates {
+		if paired[candidate.Removed] || paired[candidate.Added] {
+			continue
+		}
+		paired[candidate.Removed], paired[candidate.Added] = true, true
+		added := pairs[candidate.Added]
+		pairs[candidate.Removed] = objectPair{
+			Key:         added.Key,
+			Old:         pairs[candidate.Removed].Old,
+			New:         added.New,
+			RenamedFrom: pairs[candidate.Removed].Key,
+			Similarity:  candidate.Similarity,
+		}
+		pairs[candidate.Added] = objectPair{} // Dropped below
+	}
+
+	var result []objectPair
+	for _, pair := range pairs {
+		if pair.Old != nil || pair.New != nil {
+			result = append(result, pair)
+		}
+	}
+	return result
+}
+
+// objectSimilarity scores how alike two objects are, from 0 to 100, as the
+// share of leaf values (path and value) the two objects have in common.
+// The name and namespace are left out, since those are what a rename changes,
+// and so are apiVersion and kind, which would make any two objects look alike.
+// Objects with no other leaves, such as bare Namespaces, score 0: there is
+// nothing to show they are the same object.
+func objectSimilarity(obj1, obj2 K8sObject) int {
+	leaves1 := objectLeaves(obj1)
+	leaves2 := objectLeaves(obj2)
+
+	total, common := 0, 0
+	for leaf, n1 := range leaves1 {
+		common += min(n1, leaves2[leaf])
+		total += n1
+	}
+	for _, n2 := range leaves2 {
+		total += n2
+	}
+	if total == 0 {
+		return 0
+	}
+	return 200 * common / total
+}
+
+// objectLeaves counts the "path=value" strings of every leaf value in an object,
+// excluding apiVersion, kind, metadata.name and metadata.namespace.
+func objectLeaves(obj K8sObject) map[string]int {
+	m := objectToMap(obj)
+	delete(m, "apiVersion")
+	delete(m, "kind")
+	if metadata, ok := m["metadata"].(map[string]interface{}); ok {
+		identity := make(map[string]interface{}, len(metadata))
+		for key, val := range metadata {
+			if key != "name" && key != "namespace" {
+				identity[key] = val
+			}
+		}
+		m["metadata"] = identity
+	}
+
+	leaves := make(map[string]int)
+	var walk func(path string, val interface{})
+	walk = func(path string, val interface{}) {
+		switch v := val.(type) {
+		case map[string]interface{}:
+			for key, item := range v {
+				walk(path+"."+key, item)
+			}
+		case []interface{}:
+			for i, item := range v {
+				walk(fmt.Sprintf("%s[%d]", path, i), item)
+			}
+		default:
+			leaves[fmt.Sprintf("%s=%v", path, v)]++
+		}
+	}
+	walk("", m)
+	return leaves
+}
+
+// hashSuffixAlphabet holds the characters of a kustomize name hash: hex
+// digits with 0, 1, 3, a and e swapped for g, h, k, m and t so the hash never
+// spells a word.
+const hashSuffixAlphabet = "bcdfghkmt2456789"
+
+// stripHashSuffix removes a kustomize content hash suffix from a name
+// ("app-config-7h2k9f8m5t" becomes "app-config") and reports whether one was
+// found. Kustomize's hash is always a final dash-separated part of exactly 10
+// characters from hashSuffixAlphabet, which keeps ordinary names such as
+// "app-config-v2" or "redis-v6000" intact.
+func stripHashSuffix(name string) (string, bool) {
+	dash := strings.LastIndex(name, "-")
+	if dash <= 0 {
+		return name, false
+	}
+	suffix := name[dash+1:]
+	if len(suffix) != 10 {
+		return name, false
+	}
+	for _, r := range suffix {
+		if !strings.ContainsRune(hashSuffixAlphabet, r) {
+			return name, false
+		}
+	}
+	return name[:dash], true
+}
+
+// sameHashedName reports whether two names are the same base name with
+// different kustomize hash suffixes.
+func sameHashedName(name1, name2 string) bool {
+	base1, hashed1 := stripHashSuffix(name1)
+	base2, hashed2 := stripHashSuffix(name2)
+	return hashed1 && hashed2 && base1 == base2
+}
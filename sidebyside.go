@@ -0,0 +1,332 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// sideRow is one aligned line of the side-by-side view.
+// Left and Right hold the rendered text for each column (empty when the field
+// only exists on the other side). Status uses the same symbols as the text
+// output: ' ' unchanged, '~' modified, '-' removed, '+' added, '=' type-only.
+type sideRow struct {
+	Left   string
+	Right  string
+	Status byte
+}
+
+// writeSideBySide renders changed objects in two columns: the old object on the
+// left and the new object on the right, aligned by field path.
+//
+// Fields are matched the same way diffAnyValue matches them - map keys by name,
+// containers by container name, other arrays by index - so a changed field always
+// appears on the same line in both columns. Long values are truncated to the
+// column width, or wrapped onto continuation lines when --wrap is set.
+func writeSideBySide(w io.Writer, objects1, objects2 []K8sObject) {
+	width := opts.Width
+	if width == 0 {
+		width = detectTerminalWidth()
+	}
+	colWidth := (width - 3) / 2 // Three characters for the " ~ " gutter
+
+	first := true
+	for _, pair := range matchObjects(objects1, objects2) {
+		if pair.Old != nil && pair.New != nil && sameObject(*pair.Old, *pair.New) {
+			continue // Unchanged objects produce no output
+		}
+
+		if !first {
+			fmt.Fprintln(w)
+		}
+		first = false
+
+		// Object header names each side and where it was read from, or marks it as missing
+		header := sideRow{Status: '~'}
+		switch {
+		case pair.Old == nil:
+			header = sideRow{Right: "+++ " + pair.Key + " (added)" + sideSourceSuffix(*pair.New), Status: '+'}
+		case pair.New == nil:
+			header = sideRow{Left: "--- " + pair.Key + " (removed)" + sideSourceSuffix(*pair.Old), Status: '-'}
+		default:
+			header.Left = "--- " + oldKey(pair) + sideSourceSuffix(*pair.Old)
+			header.Right = "+++ " + pair.Key + sideSourceSuffix(*pair.New)
+			if pair.RenamedFrom != "" {
+				header.Right = fmt.Sprintf("+++ %s (renamed, %d%% similar)%s", pair.Key, pair.Similarity, sideSourceSuffix(*pair.New))
+			}
+		}
+		rows := []sideRow{header}
+
+		var oldObj, newObj K8sObject
+		if pair.Old != nil {
+			oldObj = *pair.Old
+		}
+		if pair.New != nil {
+			newObj = *pair.New
+		}
+		for _, field := range unionFields(oldObj, newObj) {
+			var val1, val2 interface{}
+			var ok1, ok2 bool
+			if pair.Old != nil {
+				val1, ok1 = objectField(*pair.Old, field)
+			}
+			if pair.New != nil {
+				val2, ok2 = objectField(*pair.New, field)
+			}
+			rows = append(rows, sideBySideRows("", field, val1, ok1, val2, ok2)...)
+		}
+
+		for _, row := range rows {
+			writeSideRow(w, row, colWidth)
+		}
+	}
+}
+
+// sideSourceSuffix returns " file:line" for an object read from a file, or "".
+func sideSourceSuffix(obj K8sObject) string {
+	if obj.Source == nil {
+		return ""
+	}
+	return " " + obj.Source.String()
+}
+
+// sideBySideRows builds the aligned rows for one field present on either side.
+// The label is the map key or list item label, and ok1/ok2 report whether the
+// field exists in the old and new object respectively.
+func sideBySideRows(indent, label string, val1 interface{}, ok1 bool, val2 interface{}, ok2 bool) []sideRow {
+	switch {
+	case !ok1 && !ok2:
+		return nil
+	case !ok1:
+		return oneSidedRows(indent, label, val2, '+')
+	case !ok2:
+		return oneSidedRows(indent, label, val1, '-')
+	case reflect.DeepEqual(val1, val2):
+		var rows []sideRow
+		for _, line := range yamlLines(indent, label, val1) {
+			rows = append(rows, sideRow{Left: line, Right: line, Status: ' '})
+		}
+		return rows
+	}
+
+	header := sideRow{Left: indent + label + ":", Right: indent + label + ":", Status: '~'}
+	childIndent := indent + "  "
+
+	switch v1 := val1.(type) {
+	case map[string]interface{}:
+		if v2, ok := val2.(map[string]interface{}); ok {
+			rows := []sideRow{header}
+			for _, key := range unionKeys(v1, v2) {
+				c1, has1 := v1[key]
+				c2, has2 := v2[key]
+				rows = append(rows, sideBySideRows(childIndent, key, c1, has1, c2, has2)...)
+			}
+			return rows
+		}
+	case []interface{}:
+		if v2, ok := val2.([]interface{}); ok {
+			rows := []sideRow{header}
+			if isContainerArray(v1) && isContainerArray(v2) {
+				// Align containers by name, in old order followed by new-only containers
+				containers1, names1 := containersByName(v1)
+				containers2, names2 := containersByName(v2)
+				for _, name := range appendMissing(names1, names2) {
+					c1, has1 := containers1[name]
+					c2, has2 := containers2[name]
+					rows = append(rows, sideBySideRows(childIndent, fmt.Sprintf("container '%s'", name), c1, has1, c2, has2)...)
+				}
+				return rows
+			}
+			for i := 0; i < max(len(v1), len(v2)); i++ {
+				var c1, c2 interface{}
+				if i < len(v1) {
+					c1 = v1[i]
+				}
+				if i < len(v2) {
+					c2 = v2[i]
+				}
+				rows = append(rows, sideBySideRows(childIndent, fmt.Sprintf("[%d]", i), c1, i < len(v1), c2, i < len(v2))...)
+			}
+			return rows
+		}
+	default:
+		if !isComposite(val2) {
+			// Two scalars share a single line
+			status := byte('~')
+			if opts.Lenient && scalarsEquivalent(val1, val2) {
+				status = '='
+			}
+			return []sideRow{{
+				Left:   fmt.Sprintf("%s%s: %s", indent, label, formatValue(val1)),
+				Right:  fmt.Sprintf("%s%s: %s", indent, label, formatValue(val2)),
+				Status: status,
+			}}
+		}
+	}
+
+	// Type mismatch - show the old value as removed and the new value as added
+	return append(oneSidedRows(indent, label, val1, '-'), oneSidedRows(indent, label, val2, '+')...)
+}
+
+// oneSidedRows renders a value that only exists on one side of the diff.
+func oneSidedRows(indent, label string, val interface{}, status byte) []sideRow {
+	var rows []sideRow
+	for _, line := range yamlLines(indent, label, val) {
+		if status == '+' {
+			rows = append(rows, sideRow{Right: line, Status: status})
+		} else {
+			rows = append(rows, sideRow{Left: line, Status: status})
+		}
+	}
+	return rows
+}
+
+// yamlLines renders a labeled value as indented YAML-like lines, using the
+// same item labels as sideBySideRows so one-sided subtrees line up visually.
+func yamlLines(indent, label string, val interface{}) []string {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		lines := []string{indent + label + ":"}
+		for _, key := range sortedKeys(v) {
+			lines = append(lines, yamlLines(indent+"  ", key, v[key])...)
+		}
+		return lines
+	case []interface{}:
+		lines := []string{indent + label + ":"}
+		containers := isContainerArray(v)
+		for i, item := range v {
+			itemLabel := fmt.Sprintf("[%d]", i)
+			if c, ok := item.(map[string]interface{}); ok && containers {
+				itemLabel = fmt.Sprintf("container '%v'", c["name"])
+			}
+			lines = append(lines, yamlLines(indent+"  ", itemLabel, item)...)
+		}
+		return lines
+	}
+	return []string{fmt.Sprintf("%s%s: %s", indent, label, formatValue(val))}
+}
+
+// unionKeys returns the keys present in either map, sorted alphabetically.
+func unionKeys(map1, map2 map[string]interface{}) []string {
+	union := make(map[string]interface{}, len(map1)+len(map2))
+	for key := range map1 {
+		union[key] = true
+	}
+	for key := range map2 {
+		union[key] = true
+	}
+	return sortedKeys(union)
+}
+
+// appendMissing returns base followed by the entries of extra not already in base.
+func appendMissing(base, extra []string) []string {
+	result := append([]string(nil), base...)
+	for _, item := range extra {
+		if !contains(base, item) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// isComposite reports whether a value is a map or a slice.
+func isComposite(val interface{}) bool {
+	switch val.(type) {
+	case map[string]interface{}, []interface{}:
+		return true
+	}
+	return false
+}
+
+// writeSideRow prints one logical row, fitting each column to colWidth.
+// Colors follow diffObject: red for removed, green for added, yellow for
+// modified and white for type-only changes. Unchanged rows stay uncolored.
+func writeSideRow(w io.Writer, row sideRow, colWidth int) {
+	leftColor, rightColor, gutterColor := "", "", ""
+	switch row.Status {
+	case '~':
+		leftColor, rightColor, gutterColor = ColorYellow, ColorYellow, ColorYellow
+	case '-':
+		leftColor, gutterColor = ColorRed, ColorRed
+	case '+':
+		rightColor, gutterColor = ColorGreen, ColorGreen
+	case '=':
+		leftColor, rightColor, gutterColor = ColorWhite, ColorWhite, ColorWhite
+	}
+
+	left := fitColumn(row.Left, colWidth)
+	right := fitColumn(row.Right, colWidth)
+	for i := 0; i < max(len(left), len(right)); i++ {
+		l, r := "", ""
+		if i < len(left) {
+			l = left[i]
+		}
+		if i < len(right) {
+			r = right[i]
+		}
+
+		// Only the first physical line of a wrapped row carries the marker
+		mark := " "
+		if i == 0 && row.Status != ' ' {
+			mark = colorize(string(row.Status), gutterColor)
+		}
+
+		// Pad outside the color codes so they don't count towards the width
+		padding := strings.Repeat(" ", colWidth-utf8.RuneCountInString(l))
+		line := fmt.Sprintf("%s%s %s %s", colorize(l, leftColor), padding, mark, colorize(r, rightColor))
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+}
+
+// fitColumn fits text into a column, truncating it with "…" or, with --wrap,
+// splitting it into several lines. Continuation lines keep the text's
+// indentation plus two spaces so wrapped values stay visually nested.
+func fitColumn(text string, colWidth int) []string {
+	if utf8.RuneCountInString(text) <= colWidth {
+		return []string{text}
+	}
+
+	runes := []rune(text)
+	if !opts.Wrap {
+		return []string{string(runes[:colWidth-1]) + "…"}
+	}
+
+	indent := len(text) - len(strings.TrimLeft(text, " ")) + 2
+	if indent >= colWidth/2 {
+		indent = 0 // Deeply nested values wrap flush left to stay readable
+	}
+	lines := []string{string(runes[:colWidth])}
+	runes = runes[colWidth:]
+	for len(runes) > 0 {
+		chunk := min(colWidth-indent, len(runes))
+		lines = append(lines, strings.Repeat(" ", indent)+string(runes[:chunk]))
+		runes = runes[chunk:]
+	}
+	return lines
+}
+
+// colorize wraps text in the given color, leaving it untouched when color is empty.
+func colorize(text, color string) string {
+	if color == "" || text == "" {
+		return text
+	}
+	return color + text + ColorReset
+}
+
+// detectTerminalWidth determines the width available for side-by-side output.
+// Checks the COLUMNS environment variable first, then asks the terminal
+// attached to stdout, and falls back to 120 columns when neither is available
+// (e.g. when output is piped to a file).
+func detectTerminalWidth() int {
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns >= 20 {
+		return columns
+	}
+	if width, ok := terminalWidth(os.Stdout); ok && width >= 20 {
+		return width
+	}
+	return 120
+}
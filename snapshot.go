@@ -0,0 +1,206 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// snapshotEntry is one object file of a snapshot.
+type snapshotEntry struct {
+	Path    string // Slash-separated path inside the snapshot
+	Content []byte
+}
+
+// runSnapshotCommand implements "k8s-diff snapshot <manifests> <output>".
+//
+// It writes the objects of a manifest file or directory - or with --live their
+// current state in the cluster - as a deterministic snapshot: one normalized,
+// key-sorted YAML file per object holding its complete document (see
+// snapshotPath for the layout). The output
+// is a new or empty directory, or a .tar.gz/.tgz archive with fixed timestamps
+// and sorted entries, so capturing unchanged state twice gives identical
+// bytes. Snapshots are ordinary inputs afterwards: two snapshots, or a
+// snapshot and manifests, are compared with the normal diff.
+func runSnapshotCommand(args []string) {
+	if len(args) != 2 {
+		fmt.Fprintf(os.Stderr, "Error: snapshot expects exactly 2 arguments (<manifests> <output>), got %d\n\n", len(args))
+		fmt.Print(helpText)
+		os.Exit(1)
+	}
+	input, output := args[0], args[1]
+
+	if err := checkFileExists(input); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	objects, err := parseK8sObjects(input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing %s: %v\n", input, err)
+		os.Exit(1)
+	}
+
+	if opts.Live {
+		fetcher, err := newLiveFetcher()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if objects, err = fetchLiveObjects(fetcher, objects); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	entries := snapshotEntries(objects)
+	if isSnapshotArchive(output) {
+		err = writeSnapshotArchive(output, entries)
+	} else {
+		err = writeSnapshotDir(output, entries)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing snapshot %s: %v\n", output, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %d object(s) to %s\n", len(entries), output)
+}
+
+// snapshotEntries renders every object to its snapshot file, sorted by path.
+// When a path occurs more than once the last object wins, as in matchObjects.
+func snapshotEntries(objects []K8sObject) []snapshotEntry {
+	byPath := make(map[string][]byte)
+	for _, obj := range objects {
+		byPath[snapshotPath(obj)] = []byte(strings.Join(normalizedYAML(obj), "\n") + "\n")
+	}
+
+	entries := make([]snapshotEntry, 0, len(byPath))
+	for file, content := range byPath {
+		entries = append(entries, snapshotEntry{Path: file, Content: content})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries
+}
+
+// snapshotPath returns the file of an object inside a snapshot:
+// <Kind>.<group>/<namespace>/<name>.yaml, or <Kind>/<namespace>/<name>.yaml for
+// the core API group. The group keeps kinds of the same name apart, e.g. a
+// Knative Service from a core Service. Objects without a namespace are stored
+// under "_".
+func snapshotPath(obj K8sObject) string {
+	dir := obj.Kind
+	if group, _, ok := strings.Cut(obj.APIVersion, "/"); ok {
+		dir += "." + group
+	}
+	namespace := getObjectNamespace(obj)
+	if namespace == "" {
+		namespace = "_"
+	}
+	return path.Join(dir, namespace, getObjectName(obj)+".yaml")
+}
+
+// writeSnapshotDir writes the entries below dir, which must not exist or be
+// empty, so a snapshot never mixes with files of an older capture.
+func writeSnapshotDir(dir string, entries []snapshotEntry) error {
+	if existing, err := os.ReadDir(dir); err == nil && len(existing) > 0 {
+		return fmt.Errorf("directory is not empty")
+	}
+	for _, entry := range entries {
+		file := filepath.Join(dir, filepath.FromSlash(entry.Path))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, entry.Content, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSnapshotArchive writes the entries as a gzip-compressed tar archive.
+// Timestamps, owners and modes are fixed so the archive is reproducible.
+func writeSnapshotArchive(file string, entries []snapshotEntry) error {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	archive := tar.NewWriter(gz)
+	for _, entry := range entries {
+		header := &tar.Header{
+			Name:     entry.Path,
+			Mode:     0o644,
+			Size:     int64(len(entry.Content)),
+			ModTime:  time.Unix(0, 0),
+			Typeflag: tar.TypeReg,
+			Format:   tar.FormatPAX,
+		}
+		if err := archive.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := archive.Write(entry.Content); err != nil {
+			return err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(file, buf.Bytes(), 0o644)
+}
+
+// isSnapshotArchive reports whether a path names a snapshot archive.
+func isSnapshotArchive(file string) bool {
+	return strings.HasSuffix(file, ".tar.gz") || strings.HasSuffix(file, ".tgz")
+}
+
+// parseSnapshotArchive reads the manifest files of a snapshot archive in
+// lexical path order. Objects are attributed to "<archive>:<path>".
+func parseSnapshotArchive(file string) ([]K8sObject, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot archive: %v", err)
+	}
+
+	var entries []snapshotEntry
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid snapshot archive: %v", err)
+		}
+		if header.Typeflag != tar.TypeReg || !isManifestFile(header.Name) {
+			continue
+		}
+		content, err := io.ReadAll(archive)
+		if err != nil {
+			return nil, fmt.Errorf("invalid snapshot archive: %v", err)
+		}
+		entries = append(entries, snapshotEntry{Path: header.Name, Content: content})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+
+	var objects []K8sObject
+	for _, entry := range entries {
+		name := file + ":" + entry.Path
+		entryObjects, err := parseManifestData(name, entry.Content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		objects = append(objects, entryObjects...)
+	}
+	return objects, nil
+}
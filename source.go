@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sourcePosition locates a value in an input file. Document is the 1-based
+// index of the YAML document within the file; Line and Column are 1-based.
+type sourcePosition struct {
+	File     string `json:"file"`
+	Document int    `json:"document"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+}
+
+// String renders the position as "file:line", the form editors and CI
+// annotations jump to.
+func (p sourcePosition) String() string {
+	return fmt.Sprintf("%s:%d", p.File, p.Line)
+}
+
+// objectSource records where an object and each of its fields were read from.
+// Fields is keyed by the JSON Pointer of each value ("/spec/containers/0/image");
+// map values are positioned at their key, array items at the item itself.
+type objectSource struct {
+	sourcePosition
+	Fields map[string]sourcePosition
+}
+
+// newObjectSource walks the root node of a parsed document and records the
+// position of the object and of every value in it.
+func newObjectSource(file string, document int, root *yaml.Node) *objectSource {
+	source := &objectSource{
+		sourcePosition: sourcePosition{File: file, Document: document, Line: root.Line, Column: root.Column},
+		Fields:         make(map[string]sourcePosition),
+	}
+
+	var walk func(pointer string, node *yaml.Node)
+	walk = func(pointer string, node *yaml.Node) {
+		if node.Kind == yaml.AliasNode && node.Alias != nil {
+			node = node.Alias
+		}
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, val := node.Content[i], node.Content[i+1]
+				child := pointer + "/" + escapeJSONPointer(key.Value)
+				source.Fields[child] = source.at(key)
+				walk(child, val)
+			}
+		case yaml.SequenceNode:
+			for i, item := range node.Content {
+				child := fmt.Sprintf("%s/%d", pointer, i)
+				source.Fields[child] = source.at(item)
+				walk(child, item)
+			}
+		}
+	}
+	walk("", root)
+
+	return source
+}
+
+// at returns the position of a node within the object's document.
+func (s *objectSource) at(node *yaml.Node) sourcePosition {
+	return sourcePosition{File: s.File, Document: s.Document, Line: node.Line, Column: node.Column}
+}
+
+// shift moves the object and all of its fields down by lines, for objects
+// parsed from a fragment that starts further down in the file.
+func (s *objectSource) shift(lines int) {
+	s.Line += lines
+	for pointer, pos := range s.Fields {
+		pos.Line += lines
+		s.Fields[pointer] = pos
+	}
+}
+
+// lookup returns the position of the value at path in the old (newSide false)
+// or new (newSide true) version of an object. When the exact value has no
+// recorded position, the closest enclosing value's position is returned.
+func (s *objectSource) lookup(path fieldPath, newSide bool) (sourcePosition, bool) {
+	if s == nil {
+		return sourcePosition{}, false
+	}
+
+	found, pos := false, s.sourcePosition
+	pointer := ""
+	for _, seg := range path {
+		switch {
+		case !seg.Item:
+			pointer += "/" + escapeJSONPointer(seg.Key)
+		case newSide && seg.NewIndex >= 0:
+			pointer += fmt.Sprintf("/%d", seg.NewIndex)
+		case !newSide && seg.OldIndex >= 0:
+			pointer += fmt.Sprintf("/%d", seg.OldIndex)
+		default:
+			return pos, found
+		}
+		next, ok := s.Fields[pointer]
+		if !ok {
+			break
+		}
+		found, pos = true, next
+	}
+	return pos, found || len(path) == 0
+}
+
+// pointerPosition returns the position of the value at a JSON Pointer in the
+// object, or of the closest enclosing value with a recorded position.
+func (s *objectSource) pointerPosition(pointer string) (sourcePosition, bool) {
+	if s == nil {
+		return sourcePosition{}, false
+	}
+	for pointer != "" {
+		if pos, ok := s.Fields[pointer]; ok {
+			return pos, true
+		}
+		pointer = pointer[:strings.LastIndex(pointer, "/")]
+	}
+	return s.sourcePosition, true
+}
+
+// changeSource returns the position a field change is reported at: the new
+// version of the field for additions and modifications, the old one for removals.
+func changeSource(pair objectPair, change fieldChange) (sourcePosition, bool) {
+	if change.Type == changeRemoved {
+		return pair.Old.Source.lookup(change.Path, false)
+	}
+	return pair.New.Source.lookup(change.Path, true)
+}
+
+// pairSource returns the position of an object: its new version, or its old
+// version when it was removed. The boolean is false when the object was not
+// read from a file (e.g. produced by the patch or merge commands).
+func pairSource(pair objectPair) (sourcePosition, bool) {
+	obj := pair.New
+	if obj == nil {
+		obj = pair.Old
+	}
+	if obj.Source == nil {
+		return sourcePosition{}, false
+	}
+	return obj.Source.sourcePosition, true
+}
+
+// sameObject reports whether two objects have the same content, ignoring
+// where they were read from.
+func sameObject(obj1, obj2 K8sObject) bool {
+	obj1.Source, obj2.Source = nil, nil
+	return reflect.DeepEqual(obj1, obj2)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// objectStat is the --stat line of one changed object.
+type objectStat struct {
+	Key    string
+	Kind   string
+	Status string
+	Counts map[changeType]int
+}
+
+// kindStat accumulates the --stat totals of one kind.
+type kindStat struct {
+	Objects map[string]int // Changed objects by status
+	Counts  map[changeType]int
+}
+
+// writeStat prints a compact overview in the spirit of "git diff --stat":
+//
+//	Deployment/prod/api    | 3 (+1 -0 ~2)
+//	ConfigMap/prod/flags   | added
+//
+//	Deployment: 1 modified, 3 field changes (+1 -0 ~2)
+//	ConfigMap: 1 added
+//
+//	2 objects changed: 1 added, 0 removed, 1 modified, 4 unchanged
+//
+// Type-only changes (--lenient) are counted separately as "=n" when present.
+func writeStat(w io.Writer, objects1, objects2 []K8sObject) {
+	var stats []objectStat
+	var kinds []string
+	byKind := make(map[string]*kindStat)
+	totals := make(map[string]int)
+
+	for _, pair := range matchObjects(objects1, objects2) {
+		status := pairStatus(pair)
+		totals[status]++
+		if status == "unchanged" {
+			continue
+		}
+
+		obj := pair.New
+		if obj == nil {
+			obj = pair.Old
+		}
+		stat := objectStat{Key: pair.Key, Kind: obj.Kind, Status: status}
+		if pair.RenamedFrom != "" {
+			stat.Key = pair.RenamedFrom + " => " + pair.Key
+		}
+		if status == "modified" {
+			stat.Counts = countChanges(objectChanges(*pair.Old, *pair.New))
+		}
+		stats = append(stats, stat)
+
+		kind, ok := byKind[obj.Kind]
+		if !ok {
+			kind = &kindStat{Objects: make(map[string]int), Counts: make(map[changeType]int)}
+			byKind[obj.Kind] = kind
+			kinds = append(kinds, obj.Kind)
+		}
+		kind.Objects[status]++
+		for typ, n := range stat.Counts {
+			kind.Counts[typ] += n
+		}
+	}
+
+	if len(stats) == 0 {
+		fmt.Fprintf(w, "No changes (%d unchanged)\n", totals["unchanged"])
+		return
+	}
+
+	keyWidth := 0
+	for _, stat := range stats {
+		keyWidth = max(keyWidth, len(stat.Key))
+	}
+	for _, stat := range stats {
+		fmt.Fprintf(w, " %-*s | %s\n", keyWidth, stat.Key, formatObjectStat(stat))
+	}
+
+	fmt.Fprintln(w)
+	for _, name := range kinds {
+		kind := byKind[name]
+		var parts []string
+		for _, status := range []string{"added", "removed", "modified"} {
+			if n := kind.Objects[status]; n > 0 {
+				parts = append(parts, fmt.Sprintf("%d %s", n, status))
+			}
+		}
+		line := strings.Join(parts, ", ")
+		if kind.Objects["modified"] > 0 {
+			line += fmt.Sprintf(", %s", formatFieldCounts(kind.Counts, true))
+		}
+		fmt.Fprintf(w, " %s: %s\n", name, line)
+	}
+
+	fmt.Fprintf(w, "\n %d object(s) changed: %s%d added%s, %s%d removed%s, %s%d modified%s, %d unchanged\n",
+		len(stats),
+		ColorGreen, totals["added"], ColorReset,
+		ColorRed, totals["removed"], ColorReset,
+		ColorYellow, totals["modified"], ColorReset,
+		totals["unchanged"])
+}
+
+// formatObjectStat renders the right-hand column of a --stat line.
+func formatObjectStat(stat objectStat) string {
+	switch stat.Status {
+	case "added":
+		return ColorGreen + "added" + ColorReset
+	case "removed":
+		return ColorRed + "removed" + ColorReset
+	}
+	if len(stat.Counts) == 0 {
+		return "reordered" // Equal apart from container order
+	}
+	return formatFieldCounts(stat.Counts, false)
+}
+
+// formatFieldCounts renders field change counts as "3 (+1 -0 ~2)", or as
+// "3 field changes (+1 -0 ~2)" when long is set.
+func formatFieldCounts(counts map[changeType]int, long bool) string {
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	label := fmt.Sprintf("%d", total)
+	if long {
+		label = fmt.Sprintf("%d field change(s)", total)
+	}
+
+	detail := fmt.Sprintf("%s+%d%s %s-%d%s %s~%d%s",
+		ColorGreen, counts[changeAdded], ColorReset,
+		ColorRed, counts[changeRemoved], ColorReset,
+		ColorYellow, counts[changeModified], ColorReset)
+	if n := counts[changeTypeOnly]; n > 0 {
+		detail += fmt.Sprintf(" %s=%d%s", ColorWhite, n, ColorReset)
+	}
+	return fmt.Sprintf("%s (%s)", label, detail)
+}
+
+// writeNames prints the identity of every changed object, one per line.
+// With status set each line is prefixed by A (added), D (removed) or
+// M (modified) and a tab, like "git diff --name-status". Renamed objects are
+// listed as R<similarity> followed by the old and new identity.
+func writeNames(w io.Writer, objects1, objects2 []K8sObject, status bool) {
+	letters := map[string]string{"added": "A", "removed": "D", "modified": "M"}
+	for _, pair := range matchObjects(objects1, objects2) {
+		letter, changed := letters[pairStatus(pair)]
+		if !changed {
+			continue
+		}
+		switch {
+		case status && pair.RenamedFrom != "":
+			fmt.Fprintf(w, "R%03d\t%s\t%s\n", pair.Similarity, pair.RenamedFrom, pair.Key)
+		case status:
+			fmt.Fprintf(w, "%s\t%s\n", letter, pair.Key)
+		default:
+			fmt.Fprintln(w, pair.Key)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package main
+
+import "os"
+
+// terminalWidth is not supported on this platform; callers fall back to
+// $COLUMNS or a fixed default width.
+func terminalWidth(f *os.File) (int, bool) {
+	return 0, false
+}
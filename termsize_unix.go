@@ -0,0 +1,22 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// terminalWidth queries the terminal window size of f with the TIOCGWINSZ ioctl.
+// Returns false when f is not a terminal.
+func terminalWidth(f *os.File) (int, bool) {
+	var size struct {
+		Rows, Cols, XPixels, YPixels uint16
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&size)))
+	if errno != 0 || size.Cols == 0 {
+		return 0, false
+	}
+	return int(size.Cols), true
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// lineEdit is a single step of a line-level edit script.
+// Op is ' ' for an unchanged line, '-' for a line only in the old text,
+// and '+' for a line only in the new text.
+type lineEdit struct {
+	Op   byte
+	Text string
+}
+
+// writeUnifiedDiff renders changed objects as a classic unified diff.
+//
+// Objects are matched with matchObjects, then each side is rendered as a
+// normalized YAML document (see normalizedYAML) and compared line by line.
+// Every changed object becomes one file section:
+//
+//	--- a/Deployment/prod/api	old.yaml:14
+//	+++ b/Deployment/prod/api	new.yaml:14
+//	@@ -12,7 +12,7 @@
+//
+// Like the timestamps of `diff -u`, the tab-separated source position of each
+// version follows its name, so patch tools still read the name alone.
+// A renamed object carries its old identity on the --- line and its new one
+// on the +++ line, like a renamed file in "diff -u" output.
+// Added objects use /dev/null as their old side and removed objects use it as
+// their new side, matching what `diff -u` and `git apply` expect.
+// Hunks carry opts.Context lines of surrounding context.
+func writeUnifiedDiff(w io.Writer, objects1, objects2 []K8sObject) {
+	for _, pair := range matchObjects(objects1, objects2) {
+		if pair.Old != nil && pair.New != nil && sameObject(*pair.Old, *pair.New) {
+			continue // Unchanged objects produce no output
+		}
+
+		oldName, newName := "/dev/null", "/dev/null"
+		var oldLines, newLines []string
+		if pair.Old != nil {
+			oldName = "a/" + oldKey(pair) + unifiedSourceSuffix(*pair.Old)
+			oldLines = normalizedYAML(*pair.Old)
+		}
+		if pair.New != nil {
+			newName = "b/" + pair.Key + unifiedSourceSuffix(*pair.New)
+			newLines = normalizedYAML(*pair.New)
+		}
+
+		edits := diffLines(oldLines, newLines)
+		if !hasLineChanges(edits) {
+			continue // Differences vanished after normalization
+		}
+
+		fmt.Fprintf(w, "--- %s\n", oldName)
+		fmt.Fprintf(w, "+++ %s\n", newName)
+		writeUnifiedHunks(w, edits, opts.Context)
+	}
+}
+
+// unifiedSourceSuffix returns a tab and the object's "file:line", or "".
+func unifiedSourceSuffix(obj K8sObject) string {
+	if obj.Source == nil {
+		return ""
+	}
+	return "\t" + obj.Source.String()
+}
+
+// normalizedYAML renders an object as YAML with a stable layout:
+// two-space indentation, top-level fields in objectFields order and all nested
+// map keys sorted alphabetically (yaml.v3 sorts map keys when marshaling).
+// Returns the document split into lines without trailing newlines.
+func normalizedYAML(obj K8sObject) []string {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(obj); err != nil {
+		// Fall back to Go formatting so the object still shows up in the diff
+		return []string{fmt.Sprintf("%v", obj)}
+	}
+	encoder.Close()
+
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+}
+
+// writeTextDiff prints a plain line-based unified diff of two texts under the
+// given file names, for files that cannot be compared as manifests. Nothing is
+// printed when the texts are equal.
+func writeTextDiff(w io.Writer, oldName, newName string, oldText, newText []byte) {
+	edits := diffLines(textLines(oldText), textLines(newText))
+	if !hasLineChanges(edits) {
+		return
+	}
+	fmt.Fprintf(w, "--- %s\n", oldName)
+	fmt.Fprintf(w, "+++ %s\n", newName)
+	writeUnifiedHunks(w, edits, opts.Context)
+}
+
+// textLines splits a text into lines without their line endings.
+func textLines(text []byte) []string {
+	if len(text) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(text), "\n"), "\n")
+}
+
+// hasLineChanges reports whether an edit script contains any insertions or deletions.
+func hasLineChanges(edits []lineEdit) bool {
+	for _, edit := range edits {
+		if edit.Op != ' ' {
+			return true
+		}
+	}
+	return false
+}
+
+// diffLines computes a minimal line-level edit script turning a into b.
+//
+// Uses Myers' O(ND) algorithm: it explores diagonals of the edit graph in order
+// of increasing edit distance D, recording the furthest-reaching point on each
+// diagonal. The recorded frontiers are then walked backwards to recover the
+// actual sequence of unchanged, deleted and inserted lines.
+func diffLines(a, b []string) []lineEdit {
+	n, m := len(a), len(b)
+	maxD := n + m
+	offset := maxD + 1
+
+	// v[offset+k] holds the furthest x reached on diagonal k (where k = x - y)
+	v := make([]int, 2*maxD+3)
+	var trace [][]int
+
+search:
+	for d := 0; d <= maxD; d++ {
+		// Snapshot the frontier before this round for backtracking
+		snapshot := make([]int, len(v))
+		copy(snapshot, v)
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // Step down: insertion
+			} else {
+				x = v[offset+k-1] + 1 // Step right: deletion
+			}
+			y := x - k
+
+			// Follow the diagonal while lines match
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Walk the trace backwards from the end point to rebuild the script
+	var edits []lineEdit
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		frontier := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && frontier[offset+k-1] < frontier[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := frontier[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			edits = append(edits, lineEdit{Op: ' ', Text: a[x-1]})
+			x--
+			y--
+		}
+
+		if d > 0 {
+			if x == prevX {
+				edits = append(edits, lineEdit{Op: '+', Text: b[y-1]})
+				y--
+			} else {
+				edits = append(edits, lineEdit{Op: '-', Text: a[x-1]})
+				x--
+			}
+		}
+	}
+
+	// Edits were collected back to front
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+// writeUnifiedHunks groups an edit script into "@@ -l,s +l,s @@" hunks.
+// Each hunk includes up to context unchanged lines before and after its changes;
+// changes separated by at most 2*context unchanged lines share a hunk.
+func writeUnifiedHunks(w io.Writer, edits []lineEdit, context int) {
+	// Line numbers (0-based) on each side at the start of every edit
+	oldPos := make([]int, len(edits)+1)
+	newPos := make([]int, len(edits)+1)
+	for i, edit := range edits {
+		oldPos[i+1], newPos[i+1] = oldPos[i], newPos[i]
+		if edit.Op != '+' {
+			oldPos[i+1]++
+		}
+		if edit.Op != '-' {
+			newPos[i+1]++
+		}
+	}
+
+	i := 0
+	for i < len(edits) {
+		// Skip ahead to the next change
+		for i < len(edits) && edits[i].Op == ' ' {
+			i++
+		}
+		if i == len(edits) {
+			break
+		}
+
+		// Extend the hunk while the next change is close enough to merge
+		start := max(i-context, 0)
+		last := i
+		for j := i + 1; j < len(edits); j++ {
+			if edits[j].Op == ' ' {
+				if j-last > 2*context {
+					break
+				}
+				continue
+			}
+			last = j
+		}
+		end := min(last+context+1, len(edits))
+
+		oldCount := oldPos[end] - oldPos[start]
+		newCount := newPos[end] - newPos[start]
+		fmt.Fprintf(w, "@@ -%s +%s @@\n", hunkRange(oldPos[start], oldCount), hunkRange(newPos[start], newCount))
+		for _, edit := range edits[start:end] {
+			fmt.Fprintf(w, "%c%s\n", edit.Op, edit.Text)
+		}
+
+		i = end
+	}
+}
+
+// hunkRange formats one side of a hunk header. By convention an empty range
+// refers to the line before it, so "0,0" describes an empty file.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}